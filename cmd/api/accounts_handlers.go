@@ -5,9 +5,11 @@ import (
 	"net/http"
 
 	"biling_api/internal/data"
+	"biling_api/internal/validator"
 )
 
 // getUserAccountsHandler returns all accounts for a user
+// Only the user's id and name are included: contacts and documents need FIDUsersRead
 func (app *application) getUserAccountsHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -34,11 +36,196 @@ func (app *application) getUserAccountsHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Only accounts covered by the operator's grant are shown
+	scope, err := app.accountScope(r, data.FIDAccountsRead)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	accounts = scope.Filter(accounts)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{
-		"user":     user,
+		"user":     envelope{"id": user.ID, "name": user.Name},
 		"accounts": accounts,
 	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// createUserAccountHandler opens an account for a business user
+// An operator limited by scopes can only create accounts inside them, e.g. with their own reseller tag
+// POST /v1/users/:id/accounts
+func (app *application) createUserAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.getScopedUser(w, r, data.FIDAccountsCreate, id)
+	if user == nil {
+		return
+	}
+
+	var input struct {
+		Region *string  `json:"region"`
+		Tags   []string `json:"tags"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	account := &data.Account{
+		Region: input.Region,
+		Tags:   input.Tags,
+	}
+
+	if account.Region != nil && *account.Region == "" {
+		account.Region = nil
+	}
+
+	v := validator.New()
+	if data.ValidateAccount(v, account); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.requireAccountInScope(w, r, data.FIDAccountsCreate, account) {
+		return
+	}
+
+	err = app.models.Accounts.Insert(account, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"account": account}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateAccountHandler changes the region and tags of an account
+// An empty region clears it; the account must stay inside the operator's scopes
+// PATCH /v1/accounts/:id
+func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	account := app.getScopedAccount(w, r, data.FIDAccountsUpdate, id)
+	if account == nil {
+		return
+	}
+
+	var input struct {
+		Region *string   `json:"region"`
+		Tags   *[]string `json:"tags"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Absent fields are kept; region can't tell absent from null, so "" clears it
+	if input.Region != nil {
+		account.Region = input.Region
+		if *input.Region == "" {
+			account.Region = nil
+		}
+	}
+	if input.Tags != nil {
+		account.Tags = *input.Tags
+	}
+
+	v := validator.New()
+	if data.ValidateAccount(v, account); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.requireAccountInScope(w, r, data.FIDAccountsUpdate, account) {
+		return
+	}
+
+	err = app.models.Accounts.Update(account)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"account": account}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAccountBalanceHandler returns the current balance derived from the ledger
+// GET /v1/accounts/:id/balance
+func (app *application) getAccountBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	account := app.getScopedAccount(w, r, data.FIDLedgerRead, id)
+	if account == nil {
+		return
+	}
+
+	balance, err := app.models.Ledger.GetBalance(account.ID, app.billing.Currency)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"balance": balance}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAccountTransactionsHandler returns ledger entries of an account, newest first
+// GET /v1/accounts/:id/transactions
+func (app *application) getAccountTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	account := app.getScopedAccount(w, r, data.FIDLedgerRead, id)
+	if account == nil {
+		return
+	}
+
+	entries, err := app.models.Ledger.GetEntriesByAccountID(account.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"account":      account,
+		"transactions": entries,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// listAPIKeysHandler returns every API key of a system account, without the keys themselves
+// GET /v1/admin/system-accounts/:id/api-keys
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.AuthUsers.GetIncludingDeleted(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	keys, err := app.models.APIKeys.GetAllForUser(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"api_keys": keys}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createAPIKeyHandler issues an API key acting as an active system account
+// Keys are issued for the caller's own account or for a service account,
+// and never reach further than the caller: every FID of the key must be held by the caller
+// with at least the scopes the account has. The key is returned only in this response
+// POST /v1/admin/system-accounts/:id/api-keys
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name      string     `json:"name"`
+		FIDs      []int64    `json:"fids"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	key := &data.APIKey{
+		UserID:    id,
+		Name:      input.Name,
+		FIDs:      input.FIDs,
+		ExpiresAt: input.ExpiresAt,
+		CreatedBy: user.ActorID(),
+	}
+
+	v := validator.New()
+	if data.ValidateAPIKey(v, key); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// A key would outlive the impersonation session
+	if user.Actor != nil {
+		app.impersonationNotAllowedResponse(w, r)
+		return
+	}
+
+	// Deleted system accounts can't get keys
+	owner, err := app.models.AuthUsers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if owner.ID != user.ID && !owner.IsService {
+		app.errorResponse(w, r, http.StatusForbidden, "API keys can only be issued for your own account or a service account")
+		return
+	}
+
+	missing, err := app.apiKeyRightsNotHeld(r, key)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(missing) > 0 {
+		app.rightsNotHeldResponse(w, r, missing)
+		return
+	}
+
+	err = app.models.APIKeys.Insert(key)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Audit.Insert(&key.CreatedBy, data.AuditAPIKeyCreated, data.AuditEntityAPIKey, key.ID, envelope{
+		"user_id":    key.UserID,
+		"name":       key.Name,
+		"fids":       key.FIDs,
+		"expires_at": key.ExpiresAt,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"api_key": key}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// apiKeyRightsNotHeld returns the codes of FIDs the key would carry beyond the caller's own rights:
+// requested FIDs the caller doesn't hold and the owner's rights the caller doesn't cover
+func (app *application) apiKeyRightsNotHeld(r *http.Request, key *data.APIKey) ([]string, error) {
+	user := app.contextGetAuthUser(r)
+	callerKey := app.contextGetAPIKey(r)
+
+	missing := []string{}
+
+	for _, fid := range key.FIDs {
+		held, err := app.models.Authorizer.HasPermission(user.ID, fid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !held || (callerKey != nil && !callerKey.Allows(fid)) {
+			missing = append(missing, functionCode(fid))
+		}
+	}
+
+	uncovered, err := app.uncoveredFIDs(user.ID, key.UserID, callerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fid := range uncovered {
+		code := functionCode(fid)
+		if key.Allows(fid) && !slices.Contains(missing, code) {
+			missing = append(missing, code)
+		}
+	}
+
+	return missing, nil
+}
+
+// revokeAPIKeyHandler revokes an API key of a system account
+// DELETE /v1/admin/system-accounts/:id/api-keys/:key_id
+func (app *application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	keyID, err := app.readInt64Param(r, "key_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	key, err := app.models.APIKeys.Revoke(id, keyID, user.ActorID())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"api_key": key}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"biling_api/internal/data"
+)
+
+func TestAPIKeyRightsNotHeld(t *testing.T) {
+	const callerID, serviceID = 1, 2
+
+	app := newTestApplication(fakeAuthorizer{
+		callerID: {
+			data.FIDAPIKeysCreate: allAccounts,
+			data.FIDAccountsRead:  allAccounts,
+			data.FIDTariffsRead:   dushanbe,
+		},
+		serviceID: {
+			data.FIDAccountsRead:  dushanbe,
+			data.FIDTariffsRead:   allAccounts,
+			data.FIDTariffsUpdate: allAccounts,
+		},
+	})
+
+	tests := []struct {
+		name      string
+		key       *data.APIKey
+		callerKey *data.APIKey
+		want      []string
+	}{
+		{"own account, every FID", &data.APIKey{UserID: callerID}, nil, []string{}},
+		{"own account, held FID", &data.APIKey{UserID: callerID, FIDs: []int64{data.FIDAccountsRead}}, nil, []string{}},
+		{"own account, FID not held", &data.APIKey{UserID: callerID, FIDs: []int64{data.FIDTariffsUpdate}}, nil, []string{"tariffs.update"}},
+		{"service, every FID", &data.APIKey{UserID: serviceID}, nil, []string{"tariffs.read", "tariffs.update"}},
+		{"service, covered FID", &data.APIKey{UserID: serviceID, FIDs: []int64{data.FIDAccountsRead}}, nil, []string{}},
+		{"service, wider scope", &data.APIKey{UserID: serviceID, FIDs: []int64{data.FIDTariffsRead}}, nil, []string{"tariffs.read"}},
+		{"through a narrower key", &data.APIKey{UserID: callerID, FIDs: []int64{data.FIDAccountsRead}}, &data.APIKey{FIDs: []int64{data.FIDAPIKeysCreate}}, []string{"accounts.read"}},
+		{"through a narrower key, every FID", &data.APIKey{UserID: callerID}, &data.APIKey{FIDs: []int64{data.FIDAPIKeysCreate}}, []string{"accounts.read", "tariffs.read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := app.contextSetAuthUser(httptest.NewRequest(http.MethodPost, "/", nil), &data.AuthUser{ID: callerID})
+			if tt.callerKey != nil {
+				r = app.contextSetAPIKey(r, tt.callerKey)
+			}
+
+			got, err := app.apiKeyRightsNotHeld(r, tt.key)
+			if err != nil {
+				t.Fatalf("apiKeyRightsNotHeld: unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("apiKeyRightsNotHeld = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
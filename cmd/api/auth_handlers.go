@@ -2,7 +2,9 @@ package main
 
 import (
 	"errors"
+	"net"
 	"net/http"
+	"slices"
 	"time"
 
 	"biling_api/internal/data"
@@ -25,6 +27,7 @@ func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 	v := validator.New()
 
 	v.Check(input.Login != "", "login", "must be provided")
+	v.Check(len(input.Login) <= 255, "login", "must not be more than 255 bytes long")
 	v.Check(input.Password != "", "password", "must be provided")
 
 	if !v.Valid() {
@@ -32,29 +35,289 @@ func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := app.models.AuthUsers.Authenticate(input.Login, input.Password)
+	user, lockedUntil, err := app.checkPassword(input.Login, input.Password, app.clientIP(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		app.loginLockedResponse(w, r, time.Until(lockedUntil))
+		return
+	}
+
+	if user == nil {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	// Second step: enrolled users and members of groups that require MFA get a challenge
+	mfaEnrolled, mfaRequired, err := app.mfaStatus(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if mfaEnrolled || mfaRequired {
+		app.writeMFAChallenge(w, r, user, mfaEnrolled)
+		return
+	}
+
+	refreshToken, err := app.models.RefreshTokens.New(user.ID, app.config.jwt.refreshTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeTokenPair(w, r, user, refreshToken, nil)
+}
+
+// checkPassword verifies a password under the login lockout
+// It returns nil without an error for wrong credentials, and the lock expiry when locked out
+func (app *application) checkPassword(login, password, ip string) (*data.AuthUser, time.Time, error) {
+	var user *data.AuthUser
+
+	ok, lockedUntil, err := app.models.LoginAttempts.Attempt(login, ip, func() (bool, error) {
+		var err error
+
+		user, err = app.models.AuthUsers.Authenticate(login, password)
+		if errors.Is(err, data.ErrInvalidCredentials) {
+			return false, nil
+		}
+
+		return err == nil, err
+	})
+	if err != nil || !ok {
+		return nil, lockedUntil, err
+	}
+
+	return user, time.Time{}, nil
+}
+
+// refreshHandler exchanges a refresh token for a new access and refresh token pair
+// POST /v1/auth/refresh
+func (app *application) refreshHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.RefreshToken != "", "refresh_token", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	refreshToken, err := app.models.RefreshTokens.Rotate(input.RefreshToken, app.config.jwt.refreshTTL)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrInvalidCredentials):
-			app.invalidCredentialsResponse(w, r)
+		case errors.Is(err, data.ErrRefreshTokenReuse):
+			app.logger.Printf("refresh token reuse detected, token family revoked")
+			app.invalidAuthenticationTokenResponse(w, r)
+		case errors.Is(err, data.ErrInvalidToken):
+			app.invalidAuthenticationTokenResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	// Generate JWT token (24 hour expiry)
-	token, err := app.models.Tokens.GenerateToken(user.ID, user.Login, 24*time.Hour)
+	user, err := app.models.AuthUsers.Get(refreshToken.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeTokenPair(w, r, user, refreshToken, nil)
+}
+
+// logoutHandler revokes the refresh token session
+// POST /v1/auth/logout
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.RefreshToken != "", "refresh_token", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.RefreshTokens.RevokeFamily(input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidToken):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "logged out"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// writeTokenPair issues a short-lived access token and responds with it and the refresh token
+// Extra fields are added to the response as is
+func (app *application) writeTokenPair(w http.ResponseWriter, r *http.Request, user *data.AuthUser, refreshToken *data.RefreshToken, extra envelope) {
+	expiresAt := time.Now().Add(app.config.jwt.accessTTL)
+
+	token, err := app.models.Tokens.GenerateToken(user.ID, user.Login, app.config.jwt.accessTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"token":                    token,
+		"token_expires_at":         expiresAt,
+		"refresh_token":            refreshToken.Plaintext,
+		"refresh_token_expires_at": refreshToken.ExpiresAt,
+		"user":                     user,
+	}
+
+	for key, value := range extra {
+		env[key] = value
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// meHandler returns the authenticated system account with its groups and effective FIDs
+// The UI uses the FID list to hide actions the operator isn't allowed to take
+// GET /v1/auth/me
+func (app *application) meHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetAuthUser(r)
+
+	groups, err := app.models.Groups.GetUserGroups(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.models.Groups.GetUserPermissions(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// With an API key only the FIDs the key allows are usable
+	if key := app.contextGetAPIKey(r); key != nil {
+		permissions = slices.DeleteFunc(permissions, func(p data.Permission) bool {
+			return !key.Allows(p.FID)
+		})
+	}
+
+	enrolled, required, err := app.mfaStatus(user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{
-		"token": token,
-		"user":  user,
+		"system_account": user,
+		"groups":         groups,
+		"permissions":    permissions,
+		"mfa": envelope{
+			"enabled":  enrolled,
+			"required": required,
+		},
 	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// jwksHandler publishes public verification keys for other services
+// GET /v1/auth/jwks.json
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "public, max-age=300")
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"keys": app.models.Tokens.Keys.JWKS()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unlockLoginHandler removes failed login counters and lockouts for a login and/or IP
+// POST /v1/admin/login-lockouts/unlock
+func (app *application) unlockLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Login string `json:"login"`
+		IP    string `json:"ip"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Login != "" || input.IP != "", "login", "login or ip must be provided")
+	v.Check(len(input.Login) <= 255, "login", "must not be more than 255 bytes long")
+
+	if input.IP != "" {
+		v.Check(net.ParseIP(input.IP) != nil, "ip", "must be a valid IP address")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	unlocked := 0
+
+	for kind, key := range map[string]string{data.AttemptsByLogin: input.Login, data.AttemptsByIP: input.IP} {
+		if key == "" {
+			continue
+		}
+
+		err = app.models.LoginAttempts.Unlock(kind, key)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				continue
+			}
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		unlocked++
+	}
+
+	if unlocked == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "login unlocked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// authzChannel is notified by triggers on system_groups and system_rights
+const authzChannel = "authz_changed"
+
+// listenAuthzChanges keeps the permission cache in sync with rights and membership changes
+// made by any instance or directly in SQL. Until listening succeeds, cached entries still expire after the TTL
+func (app *application) listenAuthzChanges(ctx context.Context) {
+	listener := pq.NewListener(app.config.db.dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			app.logger.Printf("authz listener: %v", err)
+		}
+	})
+
+	// Closing also unblocks a Listen that waits for the connection, so shutdown isn't held up
+	context.AfterFunc(ctx, func() { listener.Close() })
+
+	app.background(func() {
+		// The listener re-issues LISTEN after reconnects once it succeeded, so only the first one is retried
+		for {
+			err := listener.Listen(authzChannel)
+			if err == nil || errors.Is(err, pq.ErrChannelAlreadyOpen) {
+				break
+			}
+
+			app.logger.Printf("authz listener: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Second):
+			}
+		}
+
+		// Changes made while the LISTEN was pending weren't delivered
+		app.models.Authorizer.InvalidateAll()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				// nil is sent after a reconnect: notifications may have been missed
+				if n == nil {
+					app.models.Authorizer.InvalidateAll()
+					continue
+				}
+				app.handleAuthzNotification(n.Extra)
+			case <-time.After(90 * time.Second):
+				// Detect a dead connection when no notifications arrive
+				go listener.Ping()
+			}
+		}
+	})
+}
+
+// handleAuthzNotification applies a 'user:<id>' or '*' payload to the cache
+func (app *application) handleAuthzNotification(payload string) {
+	if s, ok := strings.CutPrefix(payload, "user:"); ok {
+		userID, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			app.models.Authorizer.Invalidate(userID)
+			return
+		}
+	}
+
+	app.models.Authorizer.InvalidateAll()
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"biling_api/internal/data"
 	"biling_api/internal/validator"
@@ -28,6 +29,10 @@ func (app *application) getAccountTariffHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if app.getScopedAccount(w, r, data.FIDTariffsRead, link.AccountID) == nil {
+		return
+	}
+
 	// Response includes version for optimistic locking
 	err = app.writeJSON(w, http.StatusOK, envelope{
 		"account_tariff": link,
@@ -37,6 +42,45 @@ func (app *application) getAccountTariffHandler(w http.ResponseWriter, r *http.R
 	}
 }
 
+// getAccountTariffHistoryHandler returns every tariff change of an account tariff link
+// GET /v1/account-tariffs/:id/history
+func (app *application) getAccountTariffHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	link, err := app.models.AccountTariffLinks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.getScopedAccount(w, r, data.FIDTariffsRead, link.AccountID) == nil {
+		return
+	}
+
+	history, err := app.models.TariffHistory.GetByLinkID(link.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"account_tariff": link,
+		"history":        history,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) changeTariffLinkHandler(w http.ResponseWriter, r *http.Request) {
 	// 1. Parse link ID from URL
 	id, err := app.readIDParam(r)
@@ -45,10 +89,27 @@ func (app *application) changeTariffLinkHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// 2. Parse request body
+	// 2. Make sure the account is within the operator's scope
+	current, err := app.models.AccountTariffLinks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.getScopedAccount(w, r, data.FIDTariffsUpdate, current.AccountID) == nil {
+		return
+	}
+
+	// 3. Parse request body
 	var input struct {
-		TariffID int64 `json:"tariff_id"`
-		Version  int64 `json:"version"` // Expected version for optimistic locking
+		TariffID    int64      `json:"tariff_id"`
+		Version     int64      `json:"version"`      // Expected version for optimistic locking
+		EffectiveAt *time.Time `json:"effective_at"` // Optional: schedule the change instead of applying it now
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -57,28 +118,63 @@ func (app *application) changeTariffLinkHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// 3. Validate input
+	// 4. Validate input
 	v := validator.New()
 	v.Check(input.TariffID > 0, "tariff_id", "must be a positive integer")
 	v.Check(input.Version > 0, "version", "must be a positive integer")
 
+	if input.EffectiveAt != nil {
+		v.Check(input.EffectiveAt.After(time.Now()), "effective_at", "must be in the future")
+	}
+
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	// 4. Get current user from context (set by auth middleware)
-	user := app.contextGetAuthUser(r)
+	// 5. Make sure the target tariff exists and can be assigned
+	tariff, err := app.models.Tariffs.Get(input.TariffID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("tariff_id", "tariff does not exist")
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	} else if !tariff.IsActive {
+		v.AddError("tariff_id", "tariff is not active")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// 6. Get current user from context (set by auth middleware); changes are recorded on the actor
+	actorID := app.contextGetAuthUser(r).ActorID()
+
+	// 7. Future-dated change is stored and applied later by the scheduler
+	if input.EffectiveAt != nil {
+		app.scheduleTariffChange(w, r, &data.ScheduledTariffChange{
+			LinkID:          id,
+			TariffID:        input.TariffID,
+			ExpectedVersion: input.Version,
+			EffectiveAt:     *input.EffectiveAt,
+			CreatedBy:       &actorID,
+		})
+		return
+	}
 
-	// 5. Prepare update with optimistic lock
+	// 8. Prepare update with optimistic lock
 	link := &data.AccountTariffLink{
 		ID:        id,
 		TariffID:  input.TariffID,
 		Version:   input.Version,
-		UpdatedBy: &user.ID,
+		UpdatedBy: &actorID,
 	}
 
-	// 6. Attempt update
+	// 9. Attempt update
 	err = app.models.AccountTariffLinks.Update(link)
 	if err != nil {
 		switch {
@@ -87,13 +183,17 @@ func (app *application) changeTariffLinkHandler(w http.ResponseWriter, r *http.R
 			app.editConflictResponse(w, r, id, input.TariffID, input.Version)
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrUnknownTariff):
+			// Tariff was deleted between the check and the update
+			v.AddError("tariff_id", "tariff does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	// 7. Return updated record
+	// 10. Return updated record
 	// Fetch full record with user info
 	updatedLink, err := app.models.AccountTariffLinks.Get(id)
 	if err != nil {
@@ -109,13 +209,135 @@ func (app *application) changeTariffLinkHandler(w http.ResponseWriter, r *http.R
 	}
 }
 
+// scheduleTariffChange stores a future-dated change and responds with 202
+func (app *application) scheduleTariffChange(w http.ResponseWriter, r *http.Request, change *data.ScheduledTariffChange) {
+	err := app.models.ScheduledChanges.Insert(change)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r, change.LinkID, change.TariffID, change.ExpectedVersion)
+		case errors.Is(err, data.ErrDuplicatePendingChange):
+			v := validator.New()
+			v.AddError("effective_at", "a pending tariff change already exists, cancel it first")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownTariff):
+			v := validator.New()
+			v.AddError("tariff_id", "tariff does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"scheduled_change": change}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listScheduledTariffChangesHandler returns pending and processed scheduled changes of a link
+// GET /v1/account-tariffs/:id/scheduled-changes
+func (app *application) listScheduledTariffChangesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	link, err := app.models.AccountTariffLinks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.getScopedAccount(w, r, data.FIDTariffsRead, link.AccountID) == nil {
+		return
+	}
+
+	changes, err := app.models.ScheduledChanges.GetByLinkID(link.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"account_tariff":    link,
+		"scheduled_changes": changes,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// cancelScheduledTariffChangeHandler cancels a pending scheduled change
+// DELETE /v1/scheduled-tariff-changes/:id
+func (app *application) cancelScheduledTariffChangeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	pending, err := app.models.ScheduledChanges.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	link, err := app.models.AccountTariffLinks.Get(pending.LinkID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.getScopedAccount(w, r, data.FIDTariffsUpdate, link.AccountID) == nil {
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	change, err := app.models.ScheduledChanges.Cancel(id, user.ActorID())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrChangeNotPending):
+			app.errorResponse(w, r, http.StatusConflict, "only pending changes can be cancelled")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"scheduled_change": change}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 // editConflictResponse returns 409 with both server and client data
 // This allows UI to show what changed and preserve user input
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request, id, clientTariffID, clientVersion int64) {
 	// Get current server state
 	serverLink, err := app.models.AccountTariffLinks.Get(id)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
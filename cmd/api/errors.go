@@ -2,7 +2,12 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"biling_api/internal/data"
 )
 
 // logError logs an error message
@@ -76,8 +81,52 @@ func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.R
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
 
-// notPermittedResponse sends a 403 Forbidden
-func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
-	message := "your user account doesn't have the necessary permissions to access this resource"
+// notPermittedResponse sends a 403 Forbidden naming the missing FID
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request, fid int64) {
+	message := map[string]interface{}{
+		"message": "your user account doesn't have the necessary permissions to access this resource",
+		"fid":     fid,
+		"code":    data.FunctionCode(fid),
+	}
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+// rightsNotHeldResponse sends a 403 Forbidden listing the permissions the user would hand out without holding them
+func (app *application) rightsNotHeldResponse(w http.ResponseWriter, r *http.Request, codes []string) {
+	message := envelope{
+		"message":     "you cannot grant permissions you don't have",
+		"permissions": codes,
+	}
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// recordConflictResponse sends a 409 Conflict for a stale version
+func (app *application) recordConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "unable to update the record due to an edit conflict, please try again"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// tariffInUseResponse sends a 409 Conflict when a tariff is still assigned to accounts
+func (app *application) tariffInUseResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the tariff is assigned to accounts and cannot be deleted, deactivate it instead"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// userHasAccountsResponse sends a 409 Conflict when a user still has accounts
+func (app *application) userHasAccountsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the user has accounts and cannot be deleted, unlink them first"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// impersonationNotAllowedResponse sends a 403 Forbidden for actions an impersonation session can't take
+func (app *application) impersonationNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this action is not available while acting as another system account"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// loginLockedResponse sends a 429 Too Many Requests with Retry-After
+func (app *application) loginLockedResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	message := "too many failed login attempts, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// writeGroup responds with a group together with its rights and members
+func (app *application) writeGroup(w http.ResponseWriter, r *http.Request, status int, id int64) {
+	group, err := app.models.Groups.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	rights, err := app.models.Groups.GetRights(group.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	members, err := app.models.Groups.GetMembers(group.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, status, envelope{
+		"group":   group,
+		"rights":  rights,
+		"members": members,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listGroupsHandler returns all groups
+// GET /v1/admin/groups
+func (app *application) listGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	groups, err := app.models.Groups.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"groups": groups}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showGroupHandler returns a group with its rights and members
+// GET /v1/admin/groups/:id
+func (app *application) showGroupHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.writeGroup(w, r, http.StatusOK, id)
+}
+
+// createGroupHandler creates an empty group
+// POST /v1/admin/groups
+func (app *application) createGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		RequireMFA  bool   `json:"require_mfa"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	group := &data.Group{
+		Name:        input.Name,
+		Description: input.Description,
+		RequireMFA:  input.RequireMFA,
+	}
+
+	v := validator.New()
+	if data.ValidateGroup(v, group); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Groups.Insert(group)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateGroupName):
+			v.AddError("name", "a group with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeGroup(w, r, http.StatusCreated, group.ID)
+}
+
+// updateGroupHandler renames a group or changes its description and MFA requirement
+// PATCH /v1/admin/groups/:id
+func (app *application) updateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	group, err := app.models.Groups.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		RequireMFA  *bool   `json:"require_mfa"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		group.Name = *input.Name
+	}
+	if input.Description != nil {
+		group.Description = *input.Description
+	}
+	if input.RequireMFA != nil {
+		group.RequireMFA = *input.RequireMFA
+	}
+
+	v := validator.New()
+	if data.ValidateGroup(v, group); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Groups.Update(group)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateGroupName):
+			v.AddError("name", "a group with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeGroup(w, r, http.StatusOK, group.ID)
+}
+
+// deleteGroupHandler deletes a group together with its rights and memberships
+// The administrators group can't be deleted
+// DELETE /v1/admin/groups/:id
+func (app *application) deleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Groups.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrProtectedGroup):
+			app.errorResponse(w, r, http.StatusConflict, "the administrators group cannot be deleted")
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "group successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// grantGroupRightHandler grants a FID to a group, optionally limited by scopes
+// Repeating the request replaces the scopes of the grant
+// The caller must hold the FID with at least these scopes
+// PUT /v1/admin/groups/:id/rights/:fid
+func (app *application) grantGroupRightHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	fid, err := app.readInt64Param(r, "fid")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// The body is optional: without scopes the grant covers every account
+	var input struct {
+		Scopes []data.Scope `json:"scopes"`
+	}
+
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	if data.ValidateScopes(v, input.Scopes); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	missing, err := app.uncoveredRights(r, []data.Permission{{FID: fid, Code: data.FunctionCode(fid), Scopes: input.Scopes}})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(missing) > 0 {
+		app.rightsNotHeldResponse(w, r, missing)
+		return
+	}
+
+	err = app.models.Groups.GrantRight(id, fid, input.Scopes)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownFunction):
+			v.AddError("fid", "unknown function id")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeGroup(w, r, http.StatusOK, id)
+}
+
+// revokeGroupRightHandler takes a FID away from a group
+// DELETE /v1/admin/groups/:id/rights/:fid
+func (app *application) revokeGroupRightHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	fid, err := app.readInt64Param(r, "fid")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Groups.RevokeRight(id, fid)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeGroup(w, r, http.StatusOK, id)
+}
+
+// addGroupMemberHandler adds an active system account to a group,
+// optionally only for the valid_from..valid_until window
+// Repeating the request replaces the window
+// The caller must hold every right of the group with at least its scopes
+// PUT /v1/admin/groups/:id/members/:user_id
+func (app *application) addGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	userID, err := app.readInt64Param(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// The body is optional: without a window the membership is permanent
+	var input struct {
+		ValidFrom  *time.Time `json:"valid_from"`
+		ValidUntil *time.Time `json:"valid_until"`
+	}
+
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	if data.ValidateMembershipWindow(v, input.ValidFrom, input.ValidUntil); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Deleted system accounts can't be added
+	_, err = app.models.AuthUsers.Get(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	rights, err := app.models.Groups.GetRights(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	missing, err := app.uncoveredRights(r, rights)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(missing) > 0 {
+		app.rightsNotHeldResponse(w, r, missing)
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	err = app.models.Groups.AddMember(id, userID, input.ValidFrom, input.ValidUntil, user.ActorID())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeGroup(w, r, http.StatusOK, id)
+}
+
+// removeGroupMemberHandler removes a system account from a group
+// The last active member of the administrators group can't be removed
+// DELETE /v1/admin/groups/:id/members/:user_id
+func (app *application) removeGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	userID, err := app.readInt64Param(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	err = app.models.Groups.RemoveMember(id, userID, user.ActorID())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrLastGroupMember):
+			app.errorResponse(w, r, http.StatusConflict, "the administrators group must keep at least one active member")
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeGroup(w, r, http.StatusOK, id)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"biling_api/internal/data"
+)
+
+func TestDeleteAdminGroup(t *testing.T) {
+	app := newTestApplication(nil)
+
+	r := httptest.NewRequest(http.MethodDelete, "/v1/admin/groups/1", nil)
+	r = app.contextSetAuthUser(withIDParam(r, data.AdminGroupID), &data.AuthUser{ID: 1})
+
+	w := httptest.NewRecorder()
+	app.deleteGroupHandler(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+}
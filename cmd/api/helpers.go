@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"biling_api/internal/validator"
+
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -27,6 +31,56 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// readInt64Param reads a positive integer URL parameter other than :id
+func (app *application) readInt64Param(r *http.Request, name string) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	n, err := strconv.ParseInt(params.ByName(name), 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+
+	return n, nil
+}
+
+// readString returns a query string value or the default when it is missing
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
+
+// readInt returns a query string value as an int or the default when it is missing
+// An invalid value is recorded in the validator
+func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
+
+// clientIP returns the remote IP address of the request without the port
+func (app *application) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // writeJSON writes arbitrary data as JSON with headers
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 	js, err := json.MarshalIndent(data, "", "\t")
@@ -101,8 +155,13 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 }
 
 // background runs a function in a background goroutine
+// The server waits for these goroutines to finish during shutdown
 func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
 	go func() {
+		defer app.wg.Done()
+
 		defer func() {
 			if err := recover(); err != nil {
 				app.logger.Printf("background: %v", err)
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"biling_api/internal/data"
+)
+
+// impersonationAllowed reports whether the actor may still act as the subject
+// The middleware checks it on every request, so an impersonation session ends
+// once the actor loses FID 29 or the subject gains rights the actor doesn't have
+func (app *application) impersonationAllowed(actorID, subjectID int64) (bool, error) {
+	canImpersonate, err := app.models.Authorizer.HasPermission(actorID, data.FIDImpersonate)
+	if err != nil || !canImpersonate {
+		return false, err
+	}
+
+	missing, err := app.uncoveredGrants(actorID, subjectID, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return len(missing) == 0, nil
+}
+
+// impersonateHandler issues an access token that acts as another system account
+// Permissions of the subject apply, actions are recorded on the actor
+// The subject's permissions, including account scopes, must not exceed the actor's own
+// POST /v1/admin/system-accounts/:id/impersonate
+func (app *application) impersonateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	actor := app.contextGetAuthUser(r)
+
+	// Impersonation doesn't chain
+	if actor.Actor != nil {
+		app.impersonationNotAllowedResponse(w, r)
+		return
+	}
+
+	// The token would carry every right of the subject, not only the FIDs of the key
+	if app.contextGetAPIKey(r) != nil {
+		app.errorResponse(w, r, http.StatusForbidden, "impersonation is not available with an API key")
+		return
+	}
+
+	if id == actor.ID {
+		app.errorResponse(w, r, http.StatusConflict, "you cannot impersonate your own system account")
+		return
+	}
+
+	missing, err := app.uncoveredGrants(actor.ID, id, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(missing) > 0 {
+		app.errorResponse(w, r, http.StatusForbidden, envelope{
+			"message":     "the system account has permissions you don't have",
+			"permissions": missing,
+		})
+		return
+	}
+
+	subject, err := app.models.AuthUsers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	token, claims, err := app.models.Tokens.GenerateImpersonationToken(subject, actor, app.config.jwt.impersonationTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Audit.Insert(&actor.ID, data.AuditImpersonationStarted, data.AuditEntitySystemAccount, subject.ID, envelope{
+		"jti":        claims.ID,
+		"expires_at": claims.ExpiresAt.Time.Format(time.RFC3339),
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	subject.Actor = actor
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"token":            token,
+		"token_expires_at": claims.ExpiresAt.Time,
+		"user":             subject,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"biling_api/internal/data"
+)
+
+func TestImpersonationAllowed(t *testing.T) {
+	const actorID, subjectID = 1, 2
+
+	tests := []struct {
+		name    string
+		actor   map[int64]*data.AccessScope
+		subject map[int64]*data.AccessScope
+		want    bool
+	}{
+		{"covered", map[int64]*data.AccessScope{data.FIDImpersonate: allAccounts, data.FIDAccountsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: dushanbe}, true},
+		{"without FID 29", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, false},
+		{"subject gained a FID", map[int64]*data.AccessScope{data.FIDImpersonate: allAccounts, data.FIDAccountsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts, data.FIDTariffsUpdate: allAccounts}, false},
+		{"subject scope widened", map[int64]*data.AccessScope{data.FIDImpersonate: allAccounts, data.FIDAccountsRead: dushanbe}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, false},
+		{"subject can impersonate too", map[int64]*data.AccessScope{data.FIDImpersonate: allAccounts}, map[int64]*data.AccessScope{data.FIDImpersonate: allAccounts}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(fakeAuthorizer{actorID: tt.actor, subjectID: tt.subject})
+
+			got, err := app.impersonationAllowed(actorID, subjectID)
+			if err != nil {
+				t.Fatalf("impersonationAllowed: unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("impersonationAllowed = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpersonateRefusals(t *testing.T) {
+	const actorID, subjectID = 1, 2
+
+	app := newTestApplication(fakeAuthorizer{
+		actorID: {
+			data.FIDImpersonate:  allAccounts,
+			data.FIDAccountsRead: dushanbe,
+		},
+		subjectID: {
+			data.FIDAccountsRead: allAccounts,
+		},
+	})
+
+	tests := []struct {
+		name            string
+		user            *data.AuthUser
+		key             *data.APIKey
+		id              int64
+		wantStatus      int
+		wantPermissions []string
+	}{
+		{"chained", &data.AuthUser{ID: actorID, Actor: &data.AuthUser{ID: 3}}, nil, subjectID, http.StatusForbidden, nil},
+		{"API key", &data.AuthUser{ID: actorID}, &data.APIKey{}, subjectID, http.StatusForbidden, nil},
+		{"self", &data.AuthUser{ID: actorID}, nil, actorID, http.StatusConflict, nil},
+		{"wider subject", &data.AuthUser{ID: actorID}, nil, subjectID, http.StatusForbidden, []string{"accounts.read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/admin/system-accounts/2/impersonate", nil)
+			r = app.contextSetAuthUser(withIDParam(r, tt.id), tt.user)
+			if tt.key != nil {
+				r = app.contextSetAPIKey(r, tt.key)
+			}
+
+			w := httptest.NewRecorder()
+			app.impersonateHandler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			if tt.wantPermissions == nil {
+				return
+			}
+
+			var body struct {
+				Error struct {
+					Permissions []string `json:"permissions"`
+				} `json:"error"`
+			}
+
+			err := json.Unmarshal(w.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+
+			if !slices.Equal(body.Error.Permissions, tt.wantPermissions) {
+				t.Errorf("permissions = %q, want %q", body.Error.Permissions, tt.wantPermissions)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"biling_api/internal/data"
+)
+
+// getAccountInvoicesHandler returns all invoices of an account
+// GET /v1/accounts/:id/invoices
+func (app *application) getAccountInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	account := app.getScopedAccount(w, r, data.FIDInvoicesRead, id)
+	if account == nil {
+		return
+	}
+
+	invoices, err := app.models.Invoices.GetByAccountID(account.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"account":  account,
+		"invoices": invoices,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showInvoiceHandler returns an invoice with its lines
+// GET /v1/invoices/:id
+func (app *application) showInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	invoice, err := app.models.Invoices.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.getScopedAccount(w, r, data.FIDInvoicesRead, invoice.AccountID) == nil {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"invoice": invoice}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"biling_api/internal/data"
+)
+
+// startBackgroundJobs launches periodic jobs that run until ctx is cancelled
+func (app *application) startBackgroundJobs(ctx context.Context) {
+	app.runPeriodically(ctx, app.config.jobs.tariffSchedulerInterval, app.applyDueTariffChanges)
+	app.runPeriodically(ctx, app.config.jobs.billingInterval, app.generateInvoices)
+	app.runPeriodically(ctx, app.config.jobs.revocationInterval, app.reloadRevocations)
+	app.runPeriodically(ctx, app.config.jobs.membershipSweepInterval, app.sweepExpiredMemberships)
+	app.listenAuthzChanges(ctx)
+}
+
+// runPeriodically calls fn immediately and then on every tick
+func (app *application) runPeriodically(ctx context.Context, interval time.Duration, fn func()) {
+	app.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			fn()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// applyDueTariffChanges applies every scheduled tariff change that has come due
+func (app *application) applyDueTariffChanges() {
+	for {
+		change, err := app.models.ScheduledChanges.ApplyNextDue()
+		if err != nil {
+			if !errors.Is(err, data.ErrNothingDue) {
+				app.logger.Printf("tariff scheduler: %v", err)
+			}
+			return
+		}
+
+		switch change.Status {
+		case data.ScheduledChangeApplied:
+			app.logger.Printf("tariff scheduler: applied change %d (link %d -> tariff %d)", change.ID, change.LinkID, change.TariffID)
+		default:
+			app.logger.Printf("tariff scheduler: change %d failed: %s", change.ID, change.FailureReason)
+		}
+	}
+}
+
+// generateInvoices issues invoices for the current month to accounts that don't have one yet
+func (app *application) generateInvoices() {
+	result, err := app.billing.GenerateMonthly(time.Now())
+	if err != nil {
+		app.logger.Printf("billing: %v", err)
+	}
+
+	if result != nil && result.Failed > 0 {
+		app.logger.Printf("billing: %d accounts failed for %s", result.Failed, result.PeriodStart.Format("2006-01"))
+	}
+
+	if result != nil && result.Created > 0 {
+		app.logger.Printf("billing: issued %d invoices for %s", result.Created, result.PeriodStart.Format("2006-01"))
+	}
+}
+
+// reloadRevocations syncs the in-memory revocation cache with the database
+func (app *application) reloadRevocations() {
+	err := app.models.Revocations.DeleteExpired()
+	if err != nil {
+		app.logger.Printf("revocations: %v", err)
+	}
+
+	err = app.models.Revocations.Load()
+	if err != nil {
+		app.logger.Printf("revocations: %v", err)
+	}
+}
+
+// sweepExpiredMemberships removes group memberships whose window has ended
+// Access already stops at valid_until; this keeps the table and the audit trail tidy
+func (app *application) sweepExpiredMemberships() {
+	entries, err := app.models.Groups.DeleteExpiredMemberships()
+	if err != nil {
+		app.logger.Printf("membership sweeper: %v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		app.logger.Printf("membership sweeper: removed expired membership %d: %s", entry.EntityID, entry.Details)
+	}
+}
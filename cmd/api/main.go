@@ -3,13 +3,20 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"biling_api/internal/billing"
 	"biling_api/internal/data"
+	"biling_api/internal/password"
 
 	"github.com/joho/godotenv"
 
@@ -29,21 +36,58 @@ type config struct {
 		maxIdleTime  string
 	}
 	jwt struct {
-		secret string
+		secret           string
+		keys             string
+		signingKID       string
+		accessTTL        time.Duration
+		refreshTTL       time.Duration
+		impersonationTTL time.Duration
+	}
+	mfa struct {
+		issuer       string
+		challengeTTL time.Duration
+	}
+	password struct {
+		minLength     int
+		denyListFile  string
+		resetTTL      time.Duration
+		algorithm     string
+		bcryptCost    int
+		argon2Time    int
+		argon2Memory  int
+		argon2Threads int
+	}
+	authz struct {
+		cacheTTL time.Duration
+	}
+	lockout struct {
+		maxFailures   int
+		maxIPFailures int
+		baseLockout   time.Duration
+		maxLockout    time.Duration
+	}
+	jobs struct {
+		tariffSchedulerInterval time.Duration
+		billingInterval         time.Duration
+		revocationInterval      time.Duration
+		membershipSweepInterval time.Duration
 	}
 }
 
 // application holds dependencies
 type application struct {
-	config config
-	logger *log.Logger
-	models data.Models
+	config  config
+	logger  *log.Logger
+	models  data.Models
+	billing billing.Service
+	wg      sync.WaitGroup
 }
 
+// init loads .env when present; without it settings come from the environment alone
 func init() {
 	err := godotenv.Load(".env")
 
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal("Error loading .env file")
 	}
 }
@@ -59,11 +103,40 @@ func main() {
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", getIntEnv("DB_MAX_IDLE_CONNS", 25), "PostgreSQL max idle connections")
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", getEnv("DB_MAX_IDLE_TIME", "15m"), "PostgreSQL max connection idle time")
 	flag.StringVar(&cfg.jwt.secret, "jwt-secret", getEnv("JWT_SECRET", ""), "JWT secret key")
+	flag.StringVar(&cfg.jwt.keys, "jwt-keys", getEnv("JWT_KEYS", ""), "Comma-separated JWT keys as kid:alg:path-to-pem (alg: RS256|EdDSA)")
+	flag.StringVar(&cfg.jwt.signingKID, "jwt-signing-kid", getEnv("JWT_SIGNING_KID", ""), "kid of the key used to sign new tokens (HS256 with jwt-secret when empty)")
+	flag.DurationVar(&cfg.jwt.accessTTL, "jwt-access-ttl", getDurationEnv("JWT_ACCESS_TTL", 15*time.Minute), "Access token lifetime")
+	flag.DurationVar(&cfg.jwt.refreshTTL, "jwt-refresh-ttl", getDurationEnv("JWT_REFRESH_TTL", 30*24*time.Hour), "Refresh token lifetime")
+	flag.DurationVar(&cfg.jwt.impersonationTTL, "jwt-impersonation-ttl", getDurationEnv("JWT_IMPERSONATION_TTL", 30*time.Minute), "Impersonation token lifetime")
+	flag.StringVar(&cfg.mfa.issuer, "mfa-issuer", getEnv("MFA_ISSUER", "Biling API"), "Issuer shown in authenticator apps")
+	flag.DurationVar(&cfg.mfa.challengeTTL, "mfa-challenge-ttl", getDurationEnv("MFA_CHALLENGE_TTL", 5*time.Minute), "How long an MFA challenge token is valid")
+	flag.IntVar(&cfg.password.minLength, "password-min-length", getIntEnv("PASSWORD_MIN_LENGTH", 8), "Minimum password length")
+	flag.StringVar(&cfg.password.denyListFile, "password-deny-list", getEnv("PASSWORD_DENY_LIST", ""), "File with forbidden passwords, one per line")
+	flag.DurationVar(&cfg.password.resetTTL, "password-reset-ttl", getDurationEnv("PASSWORD_RESET_TTL", time.Hour), "How long an admin-issued password reset token is valid")
+	flag.StringVar(&cfg.password.algorithm, "password-hash", getEnv("PASSWORD_HASH", password.AlgorithmBcrypt), "Password hash algorithm for new hashes (bcrypt|argon2id)")
+	flag.IntVar(&cfg.password.bcryptCost, "password-bcrypt-cost", getIntEnv("PASSWORD_BCRYPT_COST", password.DefaultBcryptCost), "bcrypt cost")
+	flag.IntVar(&cfg.password.argon2Time, "password-argon2-time", getIntEnv("PASSWORD_ARGON2_TIME", int(password.DefaultArgon2id.Time)), "argon2id number of passes")
+	flag.IntVar(&cfg.password.argon2Memory, "password-argon2-memory", getIntEnv("PASSWORD_ARGON2_MEMORY", int(password.DefaultArgon2id.Memory)), "argon2id memory in KiB")
+	flag.IntVar(&cfg.password.argon2Threads, "password-argon2-threads", getIntEnv("PASSWORD_ARGON2_THREADS", int(password.DefaultArgon2id.Threads)), "argon2id parallelism")
+	flag.DurationVar(&cfg.authz.cacheTTL, "authz-cache-ttl", getDurationEnv("AUTHZ_CACHE_TTL", data.DefaultAuthorizerTTL), "How long a user's permissions are cached without a change notification")
+	flag.IntVar(&cfg.lockout.maxFailures, "login-max-failures", getIntEnv("LOGIN_MAX_FAILURES", 5), "Failed logins per login before lockout")
+	flag.IntVar(&cfg.lockout.maxIPFailures, "login-max-ip-failures", getIntEnv("LOGIN_MAX_IP_FAILURES", 20), "Failed logins per IP before lockout")
+	flag.DurationVar(&cfg.lockout.baseLockout, "login-lockout", getDurationEnv("LOGIN_LOCKOUT", time.Minute), "First lockout duration, doubled on each further failure")
+	flag.DurationVar(&cfg.lockout.maxLockout, "login-max-lockout", getDurationEnv("LOGIN_MAX_LOCKOUT", time.Hour), "Maximum lockout duration")
+	flag.DurationVar(&cfg.jobs.tariffSchedulerInterval, "tariff-scheduler-interval", getDurationEnv("TARIFF_SCHEDULER_INTERVAL", time.Minute), "How often scheduled tariff changes are applied")
+	flag.DurationVar(&cfg.jobs.billingInterval, "billing-interval", getDurationEnv("BILLING_INTERVAL", time.Hour), "How often monthly invoices are generated")
+	flag.DurationVar(&cfg.jobs.revocationInterval, "revocation-refresh-interval", getDurationEnv("REVOCATION_REFRESH_INTERVAL", 30*time.Second), "How often revoked tokens are reloaded from the database")
+	flag.DurationVar(&cfg.jobs.membershipSweepInterval, "membership-sweep-interval", getDurationEnv("MEMBERSHIP_SWEEP_INTERVAL", time.Minute), "How often expired group memberships are removed")
 	flag.Parse()
 
 	// Create logger
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 
+	err := validateJobIntervals(cfg)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	// Open database connection
 	db, err := openDB(cfg)
 	if err != nil {
@@ -74,15 +147,74 @@ func main() {
 	logger.Printf("database connection pool established")
 
 	// Initialize application
+	models := data.NewModels(db)
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:  cfg,
+		logger:  logger,
+		models:  models,
+		billing: billing.New(models),
 	}
 
-	// Set JWT secret in token model
+	// Set JWT secret and keys in token model
 	app.models.Tokens.Secret = cfg.jwt.secret
 
+	if cfg.jwt.keys != "" {
+		keys, err := data.LoadKeySet(strings.Split(cfg.jwt.keys, ","), cfg.jwt.signingKID)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		app.models.Tokens.Keys = keys
+	}
+
+	app.models.Authorizer = data.NewCachedAuthorizer(db, cfg.authz.cacheTTL)
+
+	app.models.LoginAttempts.Policy = data.LockoutPolicy{
+		MaxFailures:   cfg.lockout.maxFailures,
+		MaxIPFailures: cfg.lockout.maxIPFailures,
+		BaseLockout:   cfg.lockout.baseLockout,
+		MaxLockout:    cfg.lockout.maxLockout,
+		FailureWindow: data.DefaultLockoutPolicy.FailureWindow,
+	}
+
+	app.models.AuthUsers.Policy = data.PasswordPolicy{
+		MinLength: cfg.password.minLength,
+	}
+
+	// Stored hashes with other parameters are upgraded on the next successful login
+	hasher, err := password.FromConfig(cfg.password.algorithm, cfg.password.bcryptCost, password.Argon2id{
+		Time:    uint32(cfg.password.argon2Time),
+		Memory:  uint32(cfg.password.argon2Memory),
+		Threads: uint8(cfg.password.argon2Threads),
+		KeyLen:  password.DefaultArgon2id.KeyLen,
+		SaltLen: password.DefaultArgon2id.SaltLen,
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	app.models.AuthUsers.Hasher = hasher
+	app.models.AuthUsers.Logger = logger
+
+	if cfg.password.denyListFile != "" {
+		denyList, err := data.LoadPasswordDenyList(cfg.password.denyListFile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		app.models.AuthUsers.Policy.DenyList = denyList
+	}
+
+	// FID descriptions in the database follow the registry in code
+	err = app.models.Functions.Sync(data.FunctionRegistry)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Revoked tokens must be known before the first request is served
+	err = app.models.Revocations.Load()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	// Start server
 	err = app.serve()
 	if err != nil {
@@ -110,6 +242,40 @@ func getIntEnv(env string, value int) int {
 	return n
 }
 
+func getDurationEnv(env string, value time.Duration) time.Duration {
+	v := os.Getenv(env)
+	if v == "" {
+		return value
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("warning: failed to parse %s as duration, using default value %s", env, value)
+		return value
+	}
+	return d
+}
+
+// validateJobIntervals rejects intervals that the background jobs can't tick at
+func validateJobIntervals(cfg config) error {
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"tariff-scheduler-interval (TARIFF_SCHEDULER_INTERVAL)", cfg.jobs.tariffSchedulerInterval},
+		{"billing-interval (BILLING_INTERVAL)", cfg.jobs.billingInterval},
+		{"revocation-refresh-interval (REVOCATION_REFRESH_INTERVAL)", cfg.jobs.revocationInterval},
+		{"membership-sweep-interval (MEMBERSHIP_SWEEP_INTERVAL)", cfg.jobs.membershipSweepInterval},
+	}
+
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("%s must be a positive duration, got %s", interval.name, interval.value)
+		}
+	}
+
+	return nil
+}
+
 func openDB(cfg config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", cfg.db.dsn)
 	if err != nil {
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateJobIntervals(t *testing.T) {
+	valid := func() config {
+		var cfg config
+		cfg.jobs.tariffSchedulerInterval = time.Minute
+		cfg.jobs.billingInterval = time.Hour
+		cfg.jobs.revocationInterval = 30 * time.Second
+		cfg.jobs.membershipSweepInterval = time.Minute
+		return cfg
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *config)
+		wantErr bool
+	}{
+		{"defaults", func(cfg *config) {}, false},
+		{"zero scheduler", func(cfg *config) { cfg.jobs.tariffSchedulerInterval = 0 }, true},
+		{"zero billing", func(cfg *config) { cfg.jobs.billingInterval = 0 }, true},
+		{"negative revocation", func(cfg *config) { cfg.jobs.revocationInterval = -time.Second }, true},
+		{"zero membership sweep", func(cfg *config) { cfg.jobs.membershipSweepInterval = 0 }, true},
+		{"one nanosecond", func(cfg *config) { cfg.jobs.billingInterval = time.Nanosecond }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+
+			err := validateJobIntervals(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateJobIntervals error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"biling_api/internal/data"
+	"biling_api/internal/totp"
+	"biling_api/internal/validator"
+)
+
+// mfaStatus reports whether the user has confirmed TOTP and whether their groups require it
+func (app *application) mfaStatus(userID int64) (bool, bool, error) {
+	enrolled := false
+
+	mfa, err := app.models.MFA.Get(userID)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return false, false, err
+		}
+	} else {
+		enrolled = mfa.Enabled
+	}
+
+	required, err := app.models.MFA.IsRequired(userID)
+	if err != nil {
+		return false, false, err
+	}
+
+	return enrolled, required, nil
+}
+
+// writeMFAChallenge responds to a correct password with a challenge token instead of the JWT
+func (app *application) writeMFAChallenge(w http.ResponseWriter, r *http.Request, user *data.AuthUser, enrolled bool) {
+	token, err := app.models.Tokens.GenerateChallengeToken(user.ID, user.Login, app.config.mfa.challengeTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"mfa_required":   true,
+		"mfa_enrolled":   enrolled,
+		"mfa_token":      token,
+		"mfa_expires_at": time.Now().Add(app.config.mfa.challengeTTL),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readMFAChallenge validates a challenge token and returns the user it was issued to
+// It writes the error response itself and returns nil on failure
+func (app *application) readMFAChallenge(w http.ResponseWriter, r *http.Request, mfaToken string) (*data.Claims, *data.AuthUser) {
+	claims, err := app.models.Tokens.ValidateToken(mfaToken)
+	if err != nil || claims.Purpose != data.PurposeMFAChallenge || app.models.Revocations.IsRevoked(claims) {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return nil, nil
+	}
+
+	user, err := app.models.AuthUsers.Get(claims.AuthUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, nil
+	}
+
+	return claims, user
+}
+
+// mfaEnrollHandler starts TOTP enrollment for a user who passed the password step
+// POST /v1/auth/mfa/enroll
+func (app *application) mfaEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MFAToken string `json:"mfa_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.MFAToken != "", "mfa_token", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, user := app.readMFAChallenge(w, r, input.MFAToken)
+	if user == nil {
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.MFA.SetPendingSecret(user.ID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrMFAAlreadyEnabled):
+			app.errorResponse(w, r, http.StatusConflict, "two-factor authentication is already enabled")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(secret, app.config.mfa.issuer, user.Login),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// mfaVerifyHandler exchanges a challenge token and a TOTP or recovery code for the real JWT
+// The first successful code also confirms enrollment and returns recovery codes
+// POST /v1/auth/mfa/verify
+func (app *application) mfaVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.MFAToken != "", "mfa_token", "must be provided")
+	v.Check(input.Code != "" || input.RecoveryCode != "", "code", "code or recovery_code must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	claims, user := app.readMFAChallenge(w, r, input.MFAToken)
+	if user == nil {
+		return
+	}
+
+	mfa, err := app.models.MFA.Get(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("code", "two-factor authentication is not enrolled, call /v1/auth/mfa/enroll first")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Codes are short, so guesses count towards the same lockout as passwords
+	valid, lockedUntil, err := app.models.LoginAttempts.Attempt(user.Login, app.clientIP(r), func() (bool, error) {
+		switch {
+		case input.Code != "":
+			step, ok := totp.Validate(mfa.TOTPSecret, input.Code, time.Now())
+			if !ok {
+				return false, nil
+			}
+			return app.models.MFA.UseStep(user.ID, step)
+		case mfa.Enabled:
+			return app.models.MFA.UseRecoveryCode(user.ID, input.RecoveryCode)
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		app.loginLockedResponse(w, r, time.Until(lockedUntil))
+		return
+	}
+
+	if !valid {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	// The challenge token is single-use, even across instances
+	consumed, err := app.models.Revocations.ConsumeToken(claims.ID, &user.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !consumed {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	var extra envelope
+
+	if !mfa.Enabled {
+		recoveryCodes, err := app.models.MFA.Enable(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		extra = envelope{"recovery_codes": recoveryCodes}
+	}
+
+	refreshToken, err := app.models.RefreshTokens.New(user.ID, app.config.jwt.refreshTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeTokenPair(w, r, user, refreshToken, extra)
+}
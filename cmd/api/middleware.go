@@ -13,9 +13,12 @@ import (
 // contextKey is a custom type for context keys
 type contextKey string
 
-const authUserContextKey = contextKey("authUser")
+const (
+	authUserContextKey = contextKey("authUser")
+	apiKeyContextKey   = contextKey("apiKey")
+)
 
-// authenticate validates JWT token and adds user to context
+// authenticate validates a JWT (Bearer) or an API key (ApiKey) and adds the user to context
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
@@ -28,35 +31,121 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		}
 
 		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		if len(headerParts) != 2 {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
-		token := headerParts[1]
+		switch headerParts[0] {
+		case "Bearer":
+			claims, err := app.models.Tokens.ValidateToken(headerParts[1])
+			if err != nil {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
 
-		claims, err := app.models.Tokens.ValidateToken(token)
-		if err != nil {
-			app.invalidAuthenticationTokenResponse(w, r)
-			return
-		}
+			// MFA challenge tokens and other special-purpose tokens can't access the API
+			if claims.Purpose != "" || app.models.Revocations.IsRevoked(claims) {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
 
-		user, err := app.models.AuthUsers.GetByLogin(claims.Login)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
+			user, err := app.models.AuthUsers.Get(claims.AuthUserID)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					app.invalidAuthenticationTokenResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			// A renamed account must not keep sessions issued under its old login
+			if user.Login != claims.Login {
 				app.invalidAuthenticationTokenResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			// Impersonation tokens need an actor that still exists, may still impersonate
+			// and still holds every right of the subject
+			if claims.Actor != nil {
+				actor, err := app.models.AuthUsers.Get(claims.Actor.AuthUserID)
+				if err != nil {
+					switch {
+					case errors.Is(err, data.ErrRecordNotFound):
+						app.invalidAuthenticationTokenResponse(w, r)
+					default:
+						app.serverErrorResponse(w, r, err)
+					}
+					return
+				}
+
+				allowed, err := app.impersonationAllowed(actor.ID, user.ID)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+
+				if !allowed {
+					app.invalidAuthenticationTokenResponse(w, r)
+					return
+				}
+
+				user.Actor = actor
+			}
+
+			r = app.contextSetAuthUser(r, user)
+
+		case "ApiKey":
+			key, err := app.models.APIKeys.GetByPlaintext(headerParts[1])
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrInvalidAPIKey):
+					app.invalidAuthenticationTokenResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			// Keys of deleted system accounts stop working
+			user, err := app.models.AuthUsers.Get(key.UserID)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					app.invalidAuthenticationTokenResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			// Written inline so no goroutine outlives the request; a failure doesn't block it
+			if key.NeedsTouch() {
+				err = app.models.APIKeys.Touch(key.ID)
+				if err != nil {
+					app.logger.Printf("api keys: %v", err)
+				}
 			}
+
+			r = app.contextSetAuthUser(r, user)
+			r = app.contextSetAPIKey(r, key)
+
+		default:
+			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), authUserContextKey, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
 
+// requireAuthenticatedUser only requires a valid token, without any permission
+func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return app.authenticate(next).ServeHTTP
+}
+
 // requirePermission checks if authenticated user has required permission (fid)
 func (app *application) requirePermission(fid int64, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -66,14 +155,20 @@ func (app *application) requirePermission(fid int64, next http.HandlerFunc) http
 			return
 		}
 
-		hasPermission, err := app.models.Groups.HasPermission(user.ID, fid)
+		hasPermission, err := app.models.Authorizer.HasPermission(user.ID, fid)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
 		}
 
 		if !hasPermission {
-			app.notPermittedResponse(w, r)
+			app.notPermittedResponse(w, r, fid)
+			return
+		}
+
+		// An API key may be limited to a subset of the account's FIDs
+		if key := app.contextGetAPIKey(r); key != nil && !key.Allows(fid) {
+			app.notPermittedResponse(w, r, fid)
 			return
 		}
 
@@ -84,6 +179,7 @@ func (app *application) requirePermission(fid int64, next http.HandlerFunc) http
 }
 
 // contextGetAuthUser retrieves the AuthUser from the request context
+// Permissions are checked against this user; under impersonation Actor holds who really acts
 func (app *application) contextGetAuthUser(r *http.Request) *data.AuthUser {
 	user, ok := r.Context().Value(authUserContextKey).(*data.AuthUser)
 	if !ok {
@@ -93,6 +189,28 @@ func (app *application) contextGetAuthUser(r *http.Request) *data.AuthUser {
 	return user
 }
 
+// contextSetAuthUser returns a copy of the request with the AuthUser in its context
+func (app *application) contextSetAuthUser(r *http.Request, user *data.AuthUser) *http.Request {
+	ctx := context.WithValue(r.Context(), authUserContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey returns the API key the request was authenticated with, nil for a JWT
+func (app *application) contextGetAPIKey(r *http.Request) *data.APIKey {
+	key, ok := r.Context().Value(apiKeyContextKey).(*data.APIKey)
+	if !ok {
+		return nil
+	}
+
+	return key
+}
+
+// contextSetAPIKey returns a copy of the request with the API key in its context
+func (app *application) contextSetAPIKey(r *http.Request, key *data.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
 // enableCORS enables CORS for all requests
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
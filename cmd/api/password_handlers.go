@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// changePasswordHandler lets the authenticated user change their own password
+// All sessions, including the current one, are ended afterwards
+// PUT /v1/auth/password
+func (app *application) changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// The actor must not change the subject's credentials
+	if app.contextGetAuthUser(r).Actor != nil {
+		app.impersonationNotAllowedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+		Password        string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	v := validator.New()
+	v.Check(input.CurrentPassword != "", "current_password", "must be provided")
+	v.Check(input.Password != input.CurrentPassword, "password", "must differ from the current password")
+	app.models.AuthUsers.Policy.Validate(v, user.Login, input.Password)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// A stolen session must not become a way to guess the password past the lockout
+	current, lockedUntil, err := app.checkPassword(user.Login, input.CurrentPassword, app.clientIP(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		app.loginLockedResponse(w, r, time.Until(lockedUntil))
+		return
+	}
+
+	if current == nil {
+		v.AddError("current_password", "is incorrect")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.AuthUsers.SetPassword(user.ID, input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.revokeAllTokens(user.ID, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "password changed, please log in again"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createPasswordResetHandler issues a single-use password reset token for a system account
+// The admin hands the token to the operator over a trusted channel
+// POST /v1/admin/system-accounts/:id/password-reset
+func (app *application) createPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetAuthUser(r)
+
+	// The actor must not take over accounts in the subject's name
+	if user.Actor != nil {
+		app.impersonationNotAllowedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Whoever sets the password can log in as the target, so the same limits as for impersonation apply
+	if !app.requireRightsCovered(w, r, id) {
+		return
+	}
+
+	target, err := app.models.AuthUsers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	token, err := app.models.PasswordResets.New(target.ID, app.config.password.resetTTL, user.ActorID())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"password_reset": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resetPasswordHandler sets a new password using an admin-issued reset token
+// POST /v1/auth/password/reset
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.Token != "", "token", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	userID, err := app.models.PasswordResets.GetUserID(input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidResetToken):
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.AuthUsers.Get(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.models.AuthUsers.Policy.Validate(v, user.Login, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Consume before writing the password so a token can't be used twice concurrently
+	err = app.models.PasswordResets.Consume(input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidResetToken):
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.AuthUsers.SetPassword(user.ID, input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.revokeAllTokens(user.ID, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The operator proved ownership through the admin, so a lockout no longer applies
+	err = app.models.LoginAttempts.Reset(user.Login)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "password has been reset, please log in"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+
+	"biling_api/internal/data"
+)
+
+// withIDParam returns a copy of the request carrying the :id route parameter
+func withIDParam(r *http.Request, id int64) *http.Request {
+	params := httprouter.Params{{Key: "id", Value: strconv.FormatInt(id, 10)}}
+	return r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, params))
+}
+
+func TestCreatePasswordResetRefusals(t *testing.T) {
+	const adminID, targetID, superID = 1, 2, 4
+
+	app := newTestApplication(fakeAuthorizer{
+		adminID: {
+			data.FIDPasswordReset: allAccounts,
+			data.FIDAccountsRead:  dushanbe,
+		},
+		targetID: {
+			data.FIDAccountsRead:  allAccounts,
+			data.FIDTariffsUpdate: allAccounts,
+		},
+		superID: {
+			data.FIDPasswordReset: allAccounts,
+			data.FIDAccountsRead:  allAccounts,
+			data.FIDTariffsUpdate: allAccounts,
+		},
+	})
+
+	tests := []struct {
+		name            string
+		user            *data.AuthUser
+		key             *data.APIKey
+		wantPermissions []string
+	}{
+		{"impersonating", &data.AuthUser{ID: adminID, Actor: &data.AuthUser{ID: 3}}, nil, nil},
+		{"target with wider rights", &data.AuthUser{ID: adminID}, nil, []string{"accounts.read", "tariffs.update"}},
+		{"API key limited to resets", &data.AuthUser{ID: superID}, &data.APIKey{FIDs: []int64{data.FIDPasswordReset}}, []string{"accounts.read", "tariffs.update"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/admin/system-accounts/2/password-reset", nil)
+			r = app.contextSetAuthUser(withIDParam(r, targetID), tt.user)
+			if tt.key != nil {
+				r = app.contextSetAPIKey(r, tt.key)
+			}
+
+			w := httptest.NewRecorder()
+			app.createPasswordResetHandler(w, r)
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+			}
+
+			// The impersonation refusal carries a plain message instead of a permission list
+			if tt.wantPermissions == nil {
+				return
+			}
+
+			var body struct {
+				Error struct {
+					Permissions []string `json:"permissions"`
+				} `json:"error"`
+			}
+
+			err := json.Unmarshal(w.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+
+			if !slices.Equal(body.Error.Permissions, tt.wantPermissions) {
+				t.Errorf("permissions = %q, want %q", body.Error.Permissions, tt.wantPermissions)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// createPaymentHandler records an incoming payment and allocates it to open invoices
+// POST /v1/accounts/:id/payments
+func (app *application) createPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Method      string     `json:"method"`
+		ExternalRef string     `json:"external_ref"`
+		Amount      data.Money `json:"amount"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	account := app.getScopedAccount(w, r, data.FIDPaymentsCreate, id)
+	if account == nil {
+		return
+	}
+
+	actorID := app.contextGetAuthUser(r).ActorID()
+
+	payment := &data.Payment{
+		AccountID:   account.ID,
+		Method:      input.Method,
+		ExternalRef: input.ExternalRef,
+		Amount:      input.Amount,
+		CreatedBy:   &actorID,
+	}
+
+	v := validator.New()
+
+	if data.ValidatePayment(v, payment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	created, err := app.billing.RecordPayment(payment)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPaymentRefMismatch):
+			v.AddError("external_ref", "a different payment with this reference is already recorded")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Replayed requests get 200 with the original payment
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	err = app.writeJSON(w, status, envelope{"payment": payment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"biling_api/internal/data"
+)
+
+// listPermissionsHandler returns every FID with its code, name, description and category
+// GET /v1/permissions
+func (app *application) listPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	functions, err := app.models.Functions.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"permissions": functions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// explainPermissionHandler tells why a system account has or lacks a FID:
+// every group that grants it and the account's membership in each
+// GET /v1/admin/system-accounts/:id/permissions/:fid
+func (app *application) explainPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	fid, err := app.readInt64Param(r, "fid")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	account, err := app.models.AuthUsers.GetIncludingDeleted(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	function, err := app.models.Functions.Get(fid)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	grants, err := app.models.Groups.GetGrants(fid, account.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var allowed, isMember bool
+
+	for _, g := range grants {
+		if g.Membership == nil {
+			continue
+		}
+		isMember = true
+		if g.Membership.Active {
+			allowed = true
+		}
+	}
+
+	var reason string
+
+	switch {
+	case account.IsDeleted:
+		allowed = false
+		reason = "the system account is deleted"
+	case len(grants) == 0:
+		reason = "no group grants this FID"
+	case !isMember:
+		reason = "the system account is not a member of any group that grants this FID"
+	case !allowed:
+		reason = "every membership in a group that grants this FID is outside its validity window"
+	default:
+		reason = "granted through an active group membership"
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"system_account": account,
+		"function":       function,
+		"allowed":        allowed,
+		"reason":         reason,
+		"grants":         grants,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
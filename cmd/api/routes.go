@@ -19,16 +19,164 @@ func (app *application) routes() http.Handler {
 	// Public routes
 	router.HandlerFunc(http.MethodGet, "/v1/health", app.healthcheckHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/auth/login", app.loginHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/auth/refresh", app.refreshHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/auth/logout", app.logoutHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/auth/jwks.json", app.jwksHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/auth/mfa/enroll", app.mfaEnrollHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/auth/mfa/verify", app.mfaVerifyHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/auth/password/reset", app.resetPasswordHandler)
 
 	// Protected routes
+	router.HandlerFunc(http.MethodGet, "/v1/auth/me",
+		app.requireAuthenticatedUser(app.meHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/auth/password",
+		app.requireAuthenticatedUser(app.changePasswordHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users",
+		app.requirePermission(data.FIDUsersRead, app.listUsersHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/users",
+		app.requirePermission(data.FIDUsersCreate, app.createUserHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id",
+		app.requirePermission(data.FIDUsersRead, app.showUserHandler))
+
+	router.HandlerFunc(http.MethodPatch, "/v1/users/:id",
+		app.requirePermission(data.FIDUsersUpdate, app.updateUserHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:id",
+		app.requirePermission(data.FIDUsersDelete, app.deleteUserHandler))
+
 	router.HandlerFunc(http.MethodGet, "/v1/users/:id/accounts",
 		app.requirePermission(data.FIDAccountsRead, app.getUserAccountsHandler))
 
+	router.HandlerFunc(http.MethodPost, "/v1/users/:id/accounts",
+		app.requirePermission(data.FIDAccountsCreate, app.createUserAccountHandler))
+
+	router.HandlerFunc(http.MethodPatch, "/v1/accounts/:id",
+		app.requirePermission(data.FIDAccountsUpdate, app.updateAccountHandler))
+
 	router.HandlerFunc(http.MethodGet, "/v1/account-tariffs/:id",
 		app.requirePermission(data.FIDTariffsRead, app.getAccountTariffHandler))
 
+	router.HandlerFunc(http.MethodGet, "/v1/account-tariffs/:id/history",
+		app.requirePermission(data.FIDTariffsRead, app.getAccountTariffHistoryHandler))
+
 	router.HandlerFunc(http.MethodPatch, "/v1/account-tariffs/:id",
 		app.requirePermission(data.FIDTariffsUpdate, app.changeTariffLinkHandler))
 
+	router.HandlerFunc(http.MethodGet, "/v1/account-tariffs/:id/scheduled-changes",
+		app.requirePermission(data.FIDTariffsRead, app.listScheduledTariffChangesHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/scheduled-tariff-changes/:id",
+		app.requirePermission(data.FIDTariffsUpdate, app.cancelScheduledTariffChangeHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/:id/balance",
+		app.requirePermission(data.FIDLedgerRead, app.getAccountBalanceHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/:id/transactions",
+		app.requirePermission(data.FIDLedgerRead, app.getAccountTransactionsHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/:id/payments",
+		app.requirePermission(data.FIDPaymentsCreate, app.createPaymentHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/:id/invoices",
+		app.requirePermission(data.FIDInvoicesRead, app.getAccountInvoicesHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/invoices/:id",
+		app.requirePermission(data.FIDInvoicesRead, app.showInvoiceHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/tariffs",
+		app.requirePermission(data.FIDTariffsRead, app.listTariffsHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/tariffs",
+		app.requirePermission(data.FIDTariffCatalogCreate, app.createTariffHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/tariffs/:id",
+		app.requirePermission(data.FIDTariffsRead, app.showTariffHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/tariffs/:id",
+		app.requirePermission(data.FIDTariffCatalogUpdate, app.updateTariffHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/tariffs/:id",
+		app.requirePermission(data.FIDTariffCatalogDelete, app.deleteTariffHandler))
+
+	// Admin routes
+	router.HandlerFunc(http.MethodGet, "/v1/permissions",
+		app.requirePermission(data.FIDGroupsRead, app.listPermissionsHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/tokens/revoke",
+		app.requirePermission(data.FIDTokensRevoke, app.revokeTokenHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/system-accounts",
+		app.requirePermission(data.FIDSystemAccountsRead, app.listSystemAccountsHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/system-accounts",
+		app.requirePermission(data.FIDSystemAccountsCreate, app.createSystemAccountHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/system-accounts/:id",
+		app.requirePermission(data.FIDSystemAccountsRead, app.showSystemAccountHandler))
+
+	router.HandlerFunc(http.MethodPatch, "/v1/admin/system-accounts/:id",
+		app.requirePermission(data.FIDSystemAccountsUpdate, app.updateSystemAccountHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/system-accounts/:id",
+		app.requirePermission(data.FIDSystemAccountsDelete, app.deleteSystemAccountHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/system-accounts/:id/restore",
+		app.requirePermission(data.FIDSystemAccountsDelete, app.restoreSystemAccountHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/system-accounts/:id/password-reset",
+		app.requirePermission(data.FIDPasswordReset, app.createPasswordResetHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/system-accounts/:id/revoke-tokens",
+		app.requirePermission(data.FIDTokensRevoke, app.revokeUserTokensHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/system-accounts/:id/permissions/:fid",
+		app.requirePermission(data.FIDPermissionsExplain, app.explainPermissionHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/system-accounts/:id/api-keys",
+		app.requirePermission(data.FIDAPIKeysRead, app.listAPIKeysHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/system-accounts/:id/api-keys",
+		app.requirePermission(data.FIDAPIKeysCreate, app.createAPIKeyHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/system-accounts/:id/api-keys/:key_id",
+		app.requirePermission(data.FIDAPIKeysRevoke, app.revokeAPIKeyHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/system-accounts/:id/impersonate",
+		app.requirePermission(data.FIDImpersonate, app.impersonateHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/groups",
+		app.requirePermission(data.FIDGroupsRead, app.listGroupsHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/groups",
+		app.requirePermission(data.FIDGroupsCreate, app.createGroupHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/groups/:id",
+		app.requirePermission(data.FIDGroupsRead, app.showGroupHandler))
+
+	router.HandlerFunc(http.MethodPatch, "/v1/admin/groups/:id",
+		app.requirePermission(data.FIDGroupsUpdate, app.updateGroupHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/groups/:id",
+		app.requirePermission(data.FIDGroupsDelete, app.deleteGroupHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/admin/groups/:id/rights/:fid",
+		app.requirePermission(data.FIDGroupRightsGrant, app.grantGroupRightHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/groups/:id/rights/:fid",
+		app.requirePermission(data.FIDGroupRightsRevoke, app.revokeGroupRightHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/admin/groups/:id/members/:user_id",
+		app.requirePermission(data.FIDGroupMembersAdd, app.addGroupMemberHandler))
+
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/groups/:id/members/:user_id",
+		app.requirePermission(data.FIDGroupMembersRemove, app.removeGroupMemberHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/login-lockouts/unlock",
+		app.requirePermission(data.FIDLoginUnlock, app.unlockLoginHandler))
+
 	return app.recoverPanic(app.enableCORS(router))
 }
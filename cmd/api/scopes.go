@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"biling_api/internal/data"
+)
+
+// accountScope returns which accounts the current user may reach with fid
+func (app *application) accountScope(r *http.Request, fid int64) (*data.AccessScope, error) {
+	user := app.contextGetAuthUser(r)
+
+	return app.models.Authorizer.Scope(user.ID, fid)
+}
+
+// uncoveredRights returns the codes of rights that reach further than the current user's own grants
+// A right without scopes reaches every account; with an API key only the key's FIDs count as held
+func (app *application) uncoveredRights(r *http.Request, rights []data.Permission) ([]string, error) {
+	key := app.contextGetAPIKey(r)
+
+	missing := []string{}
+
+	for _, p := range rights {
+		scope, err := app.accountScope(r, p.FID)
+		if err != nil {
+			return nil, err
+		}
+
+		granted := &data.AccessScope{Unrestricted: len(p.Scopes) == 0, Scopes: p.Scopes}
+
+		if !scope.Covers(granted) || (key != nil && !key.Allows(p.FID)) {
+			missing = append(missing, p.Code)
+		}
+	}
+
+	return missing, nil
+}
+
+// uncoveredGrants returns the codes of the subject's rights that reach further than the actor's
+// Acting as the subject must not give the actor access it doesn't have; with an API key
+// (nil otherwise) only the key's FIDs count as held
+func (app *application) uncoveredGrants(actorID, subjectID int64, key *data.APIKey) ([]string, error) {
+	fids, err := app.uncoveredFIDs(actorID, subjectID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, fid := range fids {
+		missing = append(missing, functionCode(fid))
+	}
+
+	return missing, nil
+}
+
+// uncoveredFIDs is uncoveredGrants returning FIDs in ascending order
+func (app *application) uncoveredFIDs(actorID, subjectID int64, key *data.APIKey) ([]int64, error) {
+	held, err := app.models.Authorizer.Grants(actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	granted, err := app.models.Authorizer.Grants(subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []int64{}
+
+	for fid, scope := range granted {
+		if !held[fid].Covers(scope) || (key != nil && !key.Allows(fid)) {
+			missing = append(missing, fid)
+		}
+	}
+	slices.Sort(missing)
+
+	return missing, nil
+}
+
+// functionCode returns the registry code of a FID, or the number for a FID the API doesn't know
+func functionCode(fid int64) string {
+	code := data.FunctionCode(fid)
+	if code == "" {
+		code = strconv.FormatInt(fid, 10)
+	}
+
+	return code
+}
+
+// requireRightsCovered checks that the current user holds every right of the target system account
+// It writes 403 and returns false when they don't
+func (app *application) requireRightsCovered(w http.ResponseWriter, r *http.Request, targetID int64) bool {
+	missing, err := app.uncoveredGrants(app.contextGetAuthUser(r).ID, targetID, app.contextGetAPIKey(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if len(missing) > 0 {
+		app.rightsNotHeldResponse(w, r, missing)
+		return false
+	}
+
+	return true
+}
+
+// requireAccountInScope checks that the account is covered by the user's grant of fid
+// It writes 403 and returns false when it isn't
+func (app *application) requireAccountInScope(w http.ResponseWriter, r *http.Request, fid int64, account *data.Account) bool {
+	scope, err := app.accountScope(r, fid)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !scope.Allows(account) {
+		app.notPermittedResponse(w, r, fid)
+		return false
+	}
+
+	return true
+}
+
+// getScopedAccount fetches an account the user may reach with fid
+// It writes the error response and returns nil otherwise
+func (app *application) getScopedAccount(w http.ResponseWriter, r *http.Request, fid, id int64) *data.Account {
+	account, err := app.models.Accounts.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	if !app.requireAccountInScope(w, r, fid, account) {
+		return nil
+	}
+
+	return account
+}
+
+// getScopedUser fetches a business user with at least one account the current user may reach with fid,
+// or a user without accounts that the current user created
+// It writes the error response and returns nil otherwise
+func (app *application) getScopedUser(w http.ResponseWriter, r *http.Request, fid, id int64) *data.User {
+	user, err := app.models.Users.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	scope, err := app.accountScope(r, fid)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil
+	}
+
+	if scope != nil && scope.Unrestricted {
+		return user
+	}
+
+	accounts, err := app.models.Accounts.GetByUserID(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil
+	}
+
+	if len(accounts) == 0 && user.CreatedBy != nil && *user.CreatedBy == app.contextGetAuthUser(r).ActorID() {
+		return user
+	}
+
+	if len(scope.Filter(accounts)) == 0 {
+		app.notPermittedResponse(w, r, fid)
+		return nil
+	}
+
+	return user
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"biling_api/internal/data"
+)
+
+// fakeAuthorizer serves fixed grants: user ID -> FID -> scope
+type fakeAuthorizer map[int64]map[int64]*data.AccessScope
+
+func (f fakeAuthorizer) HasPermission(userID, fid int64) (bool, error) {
+	_, ok := f[userID][fid]
+	return ok, nil
+}
+
+func (f fakeAuthorizer) Scope(userID, fid int64) (*data.AccessScope, error) {
+	return f[userID][fid], nil
+}
+
+func (f fakeAuthorizer) Grants(userID int64) (map[int64]*data.AccessScope, error) {
+	return f[userID], nil
+}
+
+func (f fakeAuthorizer) Invalidate(userID int64) {}
+
+func (f fakeAuthorizer) InvalidateAll() {}
+
+// newTestApplication returns an application that authorizes from grants and has no database
+func newTestApplication(grants fakeAuthorizer) *application {
+	return &application{
+		logger: log.New(io.Discard, "", 0),
+		models: data.Models{Authorizer: grants},
+	}
+}
+
+var (
+	allAccounts = &data.AccessScope{Unrestricted: true}
+	dushanbe    = &data.AccessScope{Scopes: []data.Scope{{Kind: data.ScopeRegion, Value: "dushanbe"}}}
+	khujand     = &data.AccessScope{Scopes: []data.Scope{{Kind: data.ScopeRegion, Value: "khujand"}}}
+)
+
+func TestUncoveredGrants(t *testing.T) {
+	const actorID, subjectID = 1, 2
+
+	tests := []struct {
+		name    string
+		actor   map[int64]*data.AccessScope
+		subject map[int64]*data.AccessScope
+		key     *data.APIKey
+		want    []string
+	}{
+		{"subject without rights", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, nil, nil, []string{}},
+		{"same rights", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, nil, []string{}},
+		{"fewer rights", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts, data.FIDTariffsUpdate: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, nil, []string{}},
+		{"missing FID", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts, data.FIDTariffsUpdate: allAccounts}, nil, []string{"tariffs.update"}},
+		{"actor without rights", nil, map[int64]*data.AccessScope{data.FIDTariffsRead: allAccounts, data.FIDAccountsRead: allAccounts}, nil, []string{"accounts.read", "tariffs.read"}},
+		{"narrower scope", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: dushanbe}, nil, []string{}},
+		{"wider scope", map[int64]*data.AccessScope{data.FIDAccountsRead: dushanbe}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, nil, []string{"accounts.read"}},
+		{"other scope", map[int64]*data.AccessScope{data.FIDAccountsRead: dushanbe}, map[int64]*data.AccessScope{data.FIDAccountsRead: khujand}, nil, []string{"accounts.read"}},
+		{"key without the FID", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts, data.FIDTariffsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts, data.FIDTariffsRead: allAccounts}, &data.APIKey{FIDs: []int64{data.FIDAccountsRead}}, []string{"tariffs.read"}},
+		{"key with every FID", map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, map[int64]*data.AccessScope{data.FIDAccountsRead: allAccounts}, &data.APIKey{}, []string{}},
+		{"unknown FID", nil, map[int64]*data.AccessScope{9999: allAccounts}, nil, []string{"9999"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(fakeAuthorizer{actorID: tt.actor, subjectID: tt.subject})
+
+			got, err := app.uncoveredGrants(actorID, subjectID, tt.key)
+			if err != nil {
+				t.Fatalf("uncoveredGrants: unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("uncoveredGrants = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUncoveredRights(t *testing.T) {
+	const userID = 1
+
+	app := newTestApplication(fakeAuthorizer{userID: {
+		data.FIDAccountsRead: allAccounts,
+		data.FIDTariffsRead:  dushanbe,
+	}})
+
+	accountsRead := data.Permission{FID: data.FIDAccountsRead, Code: "accounts.read"}
+	tariffsRead := data.Permission{FID: data.FIDTariffsRead, Code: "tariffs.read"}
+	tariffsUpdate := data.Permission{FID: data.FIDTariffsUpdate, Code: "tariffs.update"}
+
+	withScopes := func(p data.Permission, scopes ...data.Scope) data.Permission {
+		p.Scopes = scopes
+		return p
+	}
+
+	tests := []struct {
+		name   string
+		rights []data.Permission
+		key    *data.APIKey
+		want   []string
+	}{
+		{"held unrestricted", []data.Permission{accountsRead}, nil, []string{}},
+		{"held narrower", []data.Permission{withScopes(accountsRead, dushanbe.Scopes...)}, nil, []string{}},
+		{"not held", []data.Permission{tariffsUpdate}, nil, []string{"tariffs.update"}},
+		{"scope escalation", []data.Permission{tariffsRead}, nil, []string{"tariffs.read"}},
+		{"same scope", []data.Permission{withScopes(tariffsRead, dushanbe.Scopes...)}, nil, []string{}},
+		{"other scope", []data.Permission{withScopes(tariffsRead, khujand.Scopes...)}, nil, []string{"tariffs.read"}},
+		{"key without the FID", []data.Permission{accountsRead}, &data.APIKey{FIDs: []int64{data.FIDTariffsRead}}, []string{"accounts.read"}},
+		{"key with the FID", []data.Permission{accountsRead}, &data.APIKey{FIDs: []int64{data.FIDAccountsRead}}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := app.contextSetAuthUser(httptest.NewRequest("POST", "/", nil), &data.AuthUser{ID: userID})
+			if tt.key != nil {
+				r = app.contextSetAPIKey(r, tt.key)
+			}
+
+			got, err := app.uncoveredRights(r, tt.rights)
+			if err != nil {
+				t.Fatalf("uncoveredRights: unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("uncoveredRights = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
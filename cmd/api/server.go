@@ -22,6 +22,12 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	// Background jobs are stopped when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	app.startBackgroundJobs(jobsCtx)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -35,10 +41,13 @@ func (app *application) serve() error {
 		err := srv.Shutdown(ctx)
 		if err != nil {
 			shutdownError <- err
+			return
 		}
 
 		app.logger.Printf("completing background tasks (addr: %s)", srv.Addr)
 
+		stopJobs()
+		app.wg.Wait()
 		shutdownError <- nil
 	}()
 
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// listSystemAccountsHandler returns system accounts, deleted ones only on request
+// GET /v1/admin/system-accounts?include_deleted=true
+func (app *application) listSystemAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	includeDeleted := false
+	if s := r.URL.Query().Get("include_deleted"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			v.AddError("include_deleted", "must be a boolean value")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		includeDeleted = b
+	}
+
+	users, err := app.models.AuthUsers.GetAll(includeDeleted)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"system_accounts": users}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createSystemAccountHandler creates a new operator
+// POST /v1/admin/system-accounts
+func (app *application) createSystemAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		Password  string `json:"password"`
+		IsService bool   `json:"is_service"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := &data.AuthUser{
+		Login: input.Login,
+		Name:  input.Name,
+	}
+
+	v := validator.New()
+	data.ValidateAuthUser(v, user)
+	app.models.AuthUsers.Policy.Validate(v, user.Login, input.Password)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err = app.models.AuthUsers.Insert(user.Login, input.Password, user.Name, input.IsService)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateLogin):
+			v.AddError("login", "a system account with this login already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"system_account": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showSystemAccountHandler returns one system account, including deleted ones
+// GET /v1/admin/system-accounts/:id
+func (app *application) showSystemAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.AuthUsers.GetIncludingDeleted(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"system_account": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateSystemAccountHandler changes login, name and/or the service flag of an active system account
+// A login change ends the account's sessions
+// PATCH /v1/admin/system-accounts/:id
+func (app *application) updateSystemAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.AuthUsers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Login     *string `json:"login"`
+		Name      *string `json:"name"`
+		IsService *bool   `json:"is_service"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	oldLogin := user.Login
+
+	if input.Login != nil {
+		user.Login = *input.Login
+	}
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	// Operators may issue API keys for a service account, so only someone holding
+	// all of its rights may change the flag
+	if input.IsService != nil && *input.IsService != user.IsService {
+		if app.contextGetAuthUser(r).Actor != nil {
+			app.impersonationNotAllowedResponse(w, r)
+			return
+		}
+
+		if !app.requireRightsCovered(w, r, user.ID) {
+			return
+		}
+
+		user.IsService = *input.IsService
+	}
+
+	v := validator.New()
+	if data.ValidateAuthUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.AuthUsers.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateLogin):
+			v.AddError("login", "a system account with this login already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Tokens carry the login, so sessions issued under the old one end; API keys don't and stay valid
+	if user.Login != oldLogin {
+		err = app.revokeSessions(user.ID, app.contextGetAuthUser(r).ActorID())
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"system_account": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteSystemAccountHandler soft-deletes a system account and revokes its tokens and API keys
+// DELETE /v1/admin/system-accounts/:id
+func (app *application) deleteSystemAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	// Deleting yourself would lock the admin out mid-request
+	if id == user.ID {
+		app.errorResponse(w, r, http.StatusConflict, "you cannot delete your own system account")
+		return
+	}
+
+	err = app.models.AuthUsers.SoftDelete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.revokeAllTokens(id, user.ActorID())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "system account successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// restoreSystemAccountHandler clears the deleted flag of a system account
+// POST /v1/admin/system-accounts/:id/restore
+func (app *application) restoreSystemAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.AuthUsers.Restore(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateLogin):
+			v := validator.New()
+			v.AddError("login", "the login is now used by another system account")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.AuthUsers.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"system_account": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
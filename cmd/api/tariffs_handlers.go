@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// listTariffsHandler returns the whole tariff catalog
+// GET /v1/tariffs
+func (app *application) listTariffsHandler(w http.ResponseWriter, r *http.Request) {
+	tariffs, err := app.models.Tariffs.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tariffs": tariffs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createTariffHandler adds a tariff to the catalog
+// POST /v1/tariffs
+func (app *application) createTariffHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Price       data.Money `json:"price"`
+		IsActive    *bool      `json:"is_active"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tariff := &data.Tariff{
+		Name:        input.Name,
+		Description: input.Description,
+		Price:       input.Price,
+		IsActive:    true,
+	}
+
+	if input.IsActive != nil {
+		tariff.IsActive = *input.IsActive
+	}
+
+	v := validator.New()
+
+	if data.ValidateTariff(v, tariff); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Tariffs.Insert(tariff)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/tariffs/%d", tariff.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"tariff": tariff}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showTariffHandler returns a single tariff
+// GET /v1/tariffs/:id
+func (app *application) showTariffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	tariff, err := app.models.Tariffs.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tariff": tariff}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateTariffHandler replaces a tariff with optimistic locking
+// PUT /v1/tariffs/:id
+func (app *application) updateTariffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Price       data.Money `json:"price"`
+		IsActive    bool       `json:"is_active"`
+		Version     int64      `json:"version"` // Expected version for optimistic locking
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tariff := &data.Tariff{
+		ID:          id,
+		Name:        input.Name,
+		Description: input.Description,
+		Price:       input.Price,
+		IsActive:    input.IsActive,
+		Version:     input.Version,
+	}
+
+	v := validator.New()
+	v.Check(input.Version > 0, "version", "must be a positive integer")
+
+	if data.ValidateTariff(v, tariff); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Distinguish a missing tariff from a stale version
+	_, err = app.models.Tariffs.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tariffs.Update(tariff)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.recordConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	updatedTariff, err := app.models.Tariffs.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tariff": updatedTariff}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteTariffHandler removes a tariff from the catalog
+// DELETE /v1/tariffs/:id
+func (app *application) deleteTariffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Tariffs.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrTariffInUse):
+			app.tariffInUseResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "tariff successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
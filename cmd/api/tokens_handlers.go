@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// revokeTokenHandler revokes a single access token by its jti
+// POST /v1/admin/tokens/revoke
+func (app *application) revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		JTI string `json:"jti"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.JTI != "", "jti", "must be provided")
+	v.Check(len(input.JTI) <= 64, "jti", "must not be more than 64 bytes long")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	actorID := app.contextGetAuthUser(r).ActorID()
+
+	// No access token outlives the longest configured TTL, so the revocation can expire with it
+	expiresAt := time.Now().Add(max(app.config.jwt.accessTTL, app.config.jwt.impersonationTTL))
+
+	err = app.models.Revocations.RevokeToken(input.JTI, nil, expiresAt, &actorID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "token revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeUserTokensHandler revokes all access and refresh tokens and API keys of a system account
+// POST /v1/admin/system-accounts/:id/revoke-tokens
+func (app *application) revokeUserTokensHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	target, err := app.models.AuthUsers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetAuthUser(r)
+
+	err = app.revokeAllTokens(target.ID, user.ActorID())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "all tokens of the system account revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeAllTokens ends every session of a system account and revokes its API keys
+func (app *application) revokeAllTokens(userID, revokedBy int64) error {
+	err := app.revokeSessions(userID, revokedBy)
+	if err != nil {
+		return err
+	}
+
+	return app.models.APIKeys.RevokeAllForUser(userID, revokedBy)
+}
+
+// revokeSessions ends every session of a system account:
+// issued access tokens stop working and refresh tokens can't be exchanged
+func (app *application) revokeSessions(userID, revokedBy int64) error {
+	err := app.models.Revocations.RevokeAllForUser(userID, &revokedBy)
+	if err != nil {
+		return err
+	}
+
+	return app.models.RefreshTokens.RevokeAllForUser(userID)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"biling_api/internal/data"
+	"biling_api/internal/validator"
+)
+
+// listUsersHandler returns a page of business users, optionally searched by name, phone or email
+// GET /v1/users?name=&phone=&email=&page=1&page_size=20&sort=id
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	search := data.UserSearch{
+		Name:  app.readString(qs, "name", ""),
+		Phone: app.readString(qs, "phone", ""),
+		Email: app.readString(qs, "email", ""),
+	}
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "id"),
+		SortSafelist: []string{"id", "name", "created_at", "-id", "-name", "-created_at"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Operators limited to part of the accounts only see users owning such accounts
+	// and the users they created that have no accounts yet
+	scope, err := app.accountScope(r, data.FIDUsersRead)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	users, metadata, err := app.models.Users.GetAll(search, scope, app.contextGetAuthUser(r).ActorID(), filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"users": users, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createUserHandler adds a business user
+// POST /v1/users
+func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string `json:"name"`
+		Phone    string `json:"phone"`
+		Email    string `json:"email"`
+		Address  string `json:"address"`
+		Passport string `json:"passport"`
+		TaxID    string `json:"tax_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	createdBy := app.contextGetAuthUser(r).ActorID()
+
+	user := &data.User{
+		Name:      input.Name,
+		Phone:     input.Phone,
+		Email:     input.Email,
+		Address:   input.Address,
+		Passport:  input.Passport,
+		TaxID:     input.TaxID,
+		CreatedBy: &createdBy,
+	}
+
+	v := validator.New()
+	if data.ValidateUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Insert(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showUserHandler returns a business user
+// GET /v1/users/:id
+func (app *application) showUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.getScopedUser(w, r, data.FIDUsersRead, id)
+	if user == nil {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateUserHandler changes the name, contacts or documents of a business user
+// An optional version guards against overwriting someone else's changes
+// PATCH /v1/users/:id
+func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.getScopedUser(w, r, data.FIDUsersUpdate, id)
+	if user == nil {
+		return
+	}
+
+	var input struct {
+		Name     *string `json:"name"`
+		Phone    *string `json:"phone"`
+		Email    *string `json:"email"`
+		Address  *string `json:"address"`
+		Passport *string `json:"passport"`
+		TaxID    *string `json:"tax_id"`
+		Version  *int64  `json:"version"` // Expected version for optimistic locking
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Version != nil && *input.Version != user.Version {
+		app.recordConflictResponse(w, r)
+		return
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.Phone != nil {
+		user.Phone = *input.Phone
+	}
+	if input.Email != nil {
+		user.Email = *input.Email
+	}
+	if input.Address != nil {
+		user.Address = *input.Address
+	}
+	if input.Passport != nil {
+		user.Passport = *input.Passport
+	}
+	if input.TaxID != nil {
+		user.TaxID = *input.TaxID
+	}
+
+	v := validator.New()
+	if data.ValidateUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.recordConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteUserHandler removes a business user that has no accounts
+// DELETE /v1/users/:id
+func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if app.getScopedUser(w, r, data.FIDUsersDelete, id) == nil {
+		return
+	}
+
+	err = app.models.Users.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUserHasAccounts):
+			app.userHasAccountsResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
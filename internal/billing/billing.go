@@ -0,0 +1,178 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"biling_api/internal/data"
+)
+
+// DefaultCurrency is the currency of tariff prices (сомони)
+const DefaultCurrency = "TJS"
+
+// maxDescriptionLength is the length of invoice_lines.description and ledger_transactions.description
+const maxDescriptionLength = 255
+
+// Result summarises one invoice generation run
+type Result struct {
+	PeriodStart time.Time `json:"period_start"`
+	Created     int       `json:"created"`
+	Skipped     int       `json:"skipped"`
+	Failed      int       `json:"failed"`
+}
+
+// Service produces invoices from tariff assignments
+type Service struct {
+	Invoices data.InvoiceModel
+	Payments data.PaymentModel
+	Currency string
+}
+
+// New creates a billing service on top of the data models
+func New(models data.Models) Service {
+	return Service{
+		Invoices: models.Invoices,
+		Payments: models.Payments,
+		Currency: DefaultCurrency,
+	}
+}
+
+// MonthPeriod returns the first and the last day of the month containing t
+func MonthPeriod(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	return start, end
+}
+
+// GenerateMonthly issues one invoice per account with a tariff for the month containing t
+// Accounts that already have an invoice for that month are skipped, so the run is idempotent
+// A failing account doesn't stop the run; the errors of all failed accounts are returned joined
+func (s Service) GenerateMonthly(t time.Time) (*Result, error) {
+	periodStart, periodEnd := MonthPeriod(t)
+
+	accounts, err := s.Invoices.GetBillableAccounts(periodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{PeriodStart: periodStart}
+
+	var errs []error
+
+	for _, account := range accounts {
+		invoice := s.buildInvoice(account, periodStart, periodEnd)
+
+		err := s.Invoices.Insert(invoice, s.invoicePosting(invoice))
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateInvoice):
+				// Another instance issued it concurrently
+				result.Skipped++
+				continue
+			default:
+				result.Failed++
+				errs = append(errs, fmt.Errorf("account %d: %w", account.AccountID, err))
+				continue
+			}
+		}
+
+		result.Created++
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// buildInvoice turns a tariff assignment into a single-line monthly invoice
+func (s Service) buildInvoice(account *data.BillableAccount, periodStart, periodEnd time.Time) *data.Invoice {
+	tariffID := account.TariffID
+
+	line := &data.InvoiceLine{
+		TariffID:    &tariffID,
+		Description: truncate(fmt.Sprintf("Абонентская плата: %s (%s)", account.TariffName, periodStart.Format("2006-01")), maxDescriptionLength),
+		Quantity:    1,
+		UnitPrice:   account.Price,
+		Amount:      account.Price,
+	}
+
+	return &data.Invoice{
+		AccountID:   account.AccountID,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Total:       line.Amount,
+		Currency:    s.Currency,
+		Lines:       []*data.InvoiceLine{line},
+	}
+}
+
+// invoicePosting charges the invoice total to the account: Dr receivable / Cr revenue
+func (s Service) invoicePosting(invoice *data.Invoice) *data.LedgerTransaction {
+	if invoice.Total <= 0 {
+		return nil
+	}
+
+	accountID := invoice.AccountID
+
+	return &data.LedgerTransaction{
+		Description: fmt.Sprintf("Счёт за %s", invoice.PeriodStart.Format("2006-01")),
+		Entries: []*data.LedgerEntry{
+			{
+				AccountID:     &accountID,
+				LedgerAccount: data.LedgerReceivable,
+				Direction:     data.Debit,
+				Amount:        invoice.Total,
+				Currency:      invoice.Currency,
+			},
+			{
+				LedgerAccount: data.LedgerRevenue,
+				Direction:     data.Credit,
+				Amount:        invoice.Total,
+				Currency:      invoice.Currency,
+			},
+		},
+	}
+}
+
+// RecordPayment stores a payment in the billing currency and allocates it to open invoices
+// Recording the same external reference twice is a no-op that returns created = false
+func (s Service) RecordPayment(payment *data.Payment) (bool, error) {
+	payment.Currency = s.Currency
+
+	return s.Payments.Insert(payment, s.paymentPosting(payment))
+}
+
+// paymentPosting credits the account with the received amount: Dr cash / Cr receivable
+func (s Service) paymentPosting(payment *data.Payment) *data.LedgerTransaction {
+	accountID := payment.AccountID
+
+	return &data.LedgerTransaction{
+		Description: truncate(fmt.Sprintf("Платёж %s (%s)", payment.ExternalRef, payment.Method), maxDescriptionLength),
+		Entries: []*data.LedgerEntry{
+			{
+				LedgerAccount: data.LedgerCash,
+				Direction:     data.Debit,
+				Amount:        payment.Amount,
+				Currency:      payment.Currency,
+				CreatedBy:     payment.CreatedBy,
+			},
+			{
+				AccountID:     &accountID,
+				LedgerAccount: data.LedgerReceivable,
+				Direction:     data.Credit,
+				Amount:        payment.Amount,
+				Currency:      payment.Currency,
+				CreatedBy:     payment.CreatedBy,
+			},
+		},
+	}
+}
+
+// truncate cuts s to at most n characters, as VARCHAR(n) counts them
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n])
+}
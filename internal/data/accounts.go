@@ -3,12 +3,38 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
+
+	"biling_api/internal/validator"
+
+	"github.com/lib/pq"
 )
 
 // Account represents a user account (ЛС)
+// Region and tags are used by scoped permissions
 type Account struct {
-	ID int64 `json:"id"`
+	ID     int64    `json:"id"`
+	Region *string  `json:"region"`
+	Tags   []string `json:"tags"`
+}
+
+// ValidateAccount checks the region and tags that scoped permissions match against
+// The limits follow the scope values in system_right_scopes
+func ValidateAccount(v *validator.Validator, account *Account) {
+	if account.Region != nil {
+		v.Check(strings.TrimSpace(*account.Region) != "", "region", "must not be blank")
+		v.Check(len(*account.Region) <= 100, "region", "must not be more than 100 bytes long")
+	}
+
+	v.Check(len(account.Tags) <= 20, "tags", "must not contain more than 20 tags")
+	v.Check(validator.Unique(account.Tags), "tags", "must not contain duplicate values")
+
+	for _, tag := range account.Tags {
+		v.Check(strings.TrimSpace(tag) != "", "tags", "must not contain blank tags")
+		v.Check(len(tag) <= 100, "tags", "must not contain tags more than 100 bytes long")
+	}
 }
 
 // AccountModel wraps database connection
@@ -16,10 +42,40 @@ type AccountModel struct {
 	DB *sql.DB
 }
 
+// Get fetches an account by ID
+func (m AccountModel) Get(id int64) (*Account, error) {
+	query := `
+		SELECT id, region, tags
+		FROM accounts
+		WHERE id = $1`
+
+	var account Account
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&account.ID,
+		&account.Region,
+		pq.Array(&account.Tags),
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &account, nil
+}
+
 // GetByUserID fetches all accounts for a specific user
 func (m AccountModel) GetByUserID(userID int64) ([]*Account, error) {
 	query := `
-		SELECT a.id
+		SELECT a.id, a.region, a.tags
 		FROM accounts a
 		INNER JOIN users_accounts ua ON ua.account_id = a.id
 		WHERE ua.uid = $1
@@ -41,6 +97,8 @@ func (m AccountModel) GetByUserID(userID int64) ([]*Account, error) {
 
 		err := rows.Scan(
 			&account.ID,
+			&account.Region,
+			pq.Array(&account.Tags),
 		)
 		if err != nil {
 			return nil, err
@@ -55,3 +113,67 @@ func (m AccountModel) GetByUserID(userID int64) ([]*Account, error) {
 
 	return accounts, nil
 }
+
+// Insert creates an account and links it to a business user
+func (m AccountModel) Insert(account *Account, userID int64) error {
+	if account.Tags == nil {
+		account.Tags = []string{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO accounts (region, tags)
+		VALUES ($1, $2)
+		RETURNING id`, account.Region, pq.Array(account.Tags)).Scan(&account.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO users_accounts (uid, account_id)
+		VALUES ($1, $2)`, userID, account.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update changes the region and tags of an account
+func (m AccountModel) Update(account *Account) error {
+	if account.Tags == nil {
+		account.Tags = []string{}
+	}
+
+	query := `
+		UPDATE accounts
+		SET region = $2, tags = $3
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, account.ID, account.Region, pq.Array(account.Tags))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
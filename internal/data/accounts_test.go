@@ -0,0 +1,50 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"biling_api/internal/validator"
+)
+
+func TestValidateAccount(t *testing.T) {
+	tooManyTags := make([]string, 21)
+	for i := range tooManyTags {
+		tooManyTags[i] = strings.Repeat("t", i+1)
+	}
+
+	tests := []struct {
+		name    string
+		account *Account
+		wantKey string
+	}{
+		{"empty", &Account{}, ""},
+		{"region and tags", &Account{Region: stringPtr("dushanbe"), Tags: []string{"vip", "reseller-acme"}}, ""},
+		{"longest region", &Account{Region: stringPtr(strings.Repeat("r", 100))}, ""},
+		{"blank region", &Account{Region: stringPtr("  ")}, "region"},
+		{"long region", &Account{Region: stringPtr(strings.Repeat("r", 101))}, "region"},
+		{"blank tag", &Account{Tags: []string{"vip", ""}}, "tags"},
+		{"long tag", &Account{Tags: []string{strings.Repeat("t", 101)}}, "tags"},
+		{"duplicate tags", &Account{Tags: []string{"vip", "vip"}}, "tags"},
+		{"too many tags", &Account{Tags: tooManyTags}, "tags"},
+		{"twenty tags", &Account{Tags: tooManyTags[:20]}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateAccount(v, tt.account)
+
+			if tt.wantKey == "" {
+				if !v.Valid() {
+					t.Fatalf("ValidateAccount: unexpected errors %v", v.Errors)
+				}
+				return
+			}
+
+			if _, ok := v.Errors[tt.wantKey]; !ok {
+				t.Errorf("ValidateAccount errors = %v, want an error for %q", v.Errors, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"biling_api/internal/validator"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyPrefix starts every API key so leaked keys are easy to recognise
+const APIKeyPrefix = "bk_"
+
+// apiKeyLastUsedPrecision limits how often last_used_at is written for a busy key
+const apiKeyLastUsedPrecision = time.Minute
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKey is a long-lived credential of a machine client acting as a system account
+// Only the SHA-256 hash of the key is stored; the plaintext is shown once on creation
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Plaintext  string     `json:"key,omitempty"`
+	Hash       []byte     `json:"-"`
+	Prefix     string     `json:"prefix"` // First characters of the key, for telling keys apart
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	FIDs       []int64    `json:"fids"` // nil: every FID of the system account
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedBy  int64      `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Allows reports whether the key may be used for fid
+// The system account still needs the FID itself
+func (k *APIKey) Allows(fid int64) bool {
+	return k.FIDs == nil || slices.Contains(k.FIDs, fid)
+}
+
+// NeedsTouch reports whether last_used_at is old enough to be written again
+func (k *APIKey) NeedsTouch() bool {
+	return k.LastUsedAt == nil || time.Since(*k.LastUsedAt) >= apiKeyLastUsedPrecision
+}
+
+// ValidateAPIKey checks the fields set by the client
+func ValidateAPIKey(v *validator.Validator, key *APIKey) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 255, "name", "must not be more than 255 bytes long")
+
+	if key.FIDs != nil {
+		v.Check(len(key.FIDs) > 0, "fids", "must contain at least one FID or be omitted")
+		v.Check(validator.Unique(key.FIDs), "fids", "must not contain duplicate values")
+
+		for _, fid := range key.FIDs {
+			v.Check(FunctionCode(fid) != "", "fids", "must contain only known FIDs")
+		}
+	}
+
+	if key.ExpiresAt != nil {
+		v.Check(key.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+}
+
+// APIKeyModel handles API keys
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// hashAPIKey returns the lookup hash of a plaintext key
+func hashAPIKey(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+const apiKeyColumns = `id, prefix, user_id, name, fids, expires_at, last_used_at, created_by, created_at, revoked_at`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	var key APIKey
+	var fids pq.Int64Array
+
+	err := row.Scan(
+		&key.ID,
+		&key.Prefix,
+		&key.UserID,
+		&key.Name,
+		&fids,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if fids != nil {
+		key.FIDs = []int64(fids)
+	}
+
+	return &key, nil
+}
+
+// Insert generates the key, stores its hash and fills in ID, Prefix, Plaintext and CreatedAt
+// Returns ErrRecordNotFound when the system account doesn't exist
+func (m APIKeyModel) Insert(key *APIKey) error {
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	key.Plaintext = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+	key.Prefix = key.Plaintext[:len(APIKeyPrefix)+8]
+	key.Hash = hashAPIKey(key.Plaintext)
+
+	var fids interface{}
+	if key.FIDs != nil {
+		fids = pq.Array(key.FIDs)
+	}
+
+	query := `
+		INSERT INTO api_keys (key_hash, prefix, user_id, name, fids, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	args := []interface{}{key.Hash, key.Prefix, key.UserID, key.Name, fids, key.ExpiresAt, key.CreatedBy}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "api_keys" violates foreign key constraint "api_keys_user_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByPlaintext returns a usable key: not revoked and not expired
+// Anything else, including strings that don't look like a key, returns ErrInvalidAPIKey
+func (m APIKeyModel) GetByPlaintext(plaintext string) (*APIKey, error) {
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE key_hash = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	key, err := scanAPIKey(m.DB.QueryRowContext(ctx, query, hashAPIKey(plaintext)))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrInvalidAPIKey
+		default:
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// GetAllForUser returns every key of a system account, including revoked and expired ones
+func (m APIKeyModel) GetAllForUser(userID int64) ([]*APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes one key of a system account; revoking it again is a no-op
+func (m APIKeyModel) Revoke(userID, id, revokedBy int64) (*APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, NOW()),
+			revoked_by = COALESCE(revoked_by, $3)
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + apiKeyColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	key, err := scanAPIKey(m.DB.QueryRowContext(ctx, query, id, userID, revokedBy))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// RevokeAllForUser revokes every key of a system account
+func (m APIKeyModel) RevokeAllForUser(userID, revokedBy int64) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = NOW(), revoked_by = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, revokedBy)
+	return err
+}
+
+// Touch records that the key was just used
+// The row is written at most once per apiKeyLastUsedPrecision, however many instances use the key
+func (m APIKeyModel) Touch(id int64) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+		  AND (last_used_at IS NULL OR last_used_at <= NOW() - make_interval(secs => $2))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, apiKeyLastUsedPrecision.Seconds())
+	return err
+}
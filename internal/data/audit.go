@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditMembershipAdded   = "membership.added"
+	AuditMembershipRemoved = "membership.removed"
+	AuditMembershipExpired = "membership.expired"
+
+	AuditImpersonationStarted = "impersonation.started"
+
+	AuditAPIKeyCreated = "api_key.created"
+)
+
+// Audited entities; entity_id is the row ID in the table of the same name
+const (
+	AuditEntityMembership    = "system_groups"
+	AuditEntitySystemAccount = "system_accounts"
+	AuditEntityAPIKey        = "api_keys"
+)
+
+// AuditEntry is one record of the audit trail
+// ActorID is nil for actions performed by the system itself
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	ActorID   *int64          `json:"actor_id"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  int64           `json:"entity_id"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+const auditEntryColumns = `id, actor_id, action, entity, entity_id, details, created_at`
+
+func scanAuditEntry(row interface{ Scan(...interface{}) error }) (*AuditEntry, error) {
+	var entry AuditEntry
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.ActorID,
+		&entry.Action,
+		&entry.Entity,
+		&entry.EntityID,
+		&entry.Details,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// AuditModel writes the audit trail
+type AuditModel struct {
+	DB *sql.DB
+}
+
+// Insert records an action; details are stored as JSON
+// actorID is nil for actions performed by the system itself
+func (m AuditModel) Insert(actorID *int64, action, entity string, entityID int64, details interface{}) error {
+	js, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity, entity_id, details)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, actorID, action, entity, entityID, js)
+	return err
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"log"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"biling_api/internal/password"
+	"biling_api/internal/validator"
 )
 
 var (
@@ -15,42 +17,91 @@ var (
 )
 
 // AuthUser represents a system authentication user
+// Actor is set when another system account acts as this one (impersonation)
+// IsService marks an account of a machine client that operators may issue API keys for
 type AuthUser struct {
 	ID        int64     `json:"id"`
 	Login     string    `json:"login"`
+	Name      string    `json:"name"`
 	Password  string    `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
+	IsDeleted bool      `json:"is_deleted"`
+	IsService bool      `json:"is_service"`
+	Actor     *AuthUser `json:"actor,omitempty"`
+}
+
+// ActorID returns who really performs the request: the impersonating actor if any, otherwise the user
+// It is the ID to record in audit entries and created_by/updated_by columns
+func (u *AuthUser) ActorID() int64 {
+	if u.Actor != nil {
+		return u.Actor.ID
+	}
+
+	return u.ID
+}
+
+// ValidateAuthUser checks system account fields before they are written
+func ValidateAuthUser(v *validator.Validator, user *AuthUser) {
+	v.Check(user.Login != "", "login", "must be provided")
+	v.Check(len(user.Login) <= 255, "login", "must not be more than 255 bytes long")
+
+	v.Check(user.Name != "", "name", "must be provided")
+	v.Check(len(user.Name) <= 100, "name", "must not be more than 100 bytes long")
 }
 
 // AuthUserModel wraps database connection
+// Logger receives failures that don't fail the call, such as a hash upgrade; nil discards them
 type AuthUserModel struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Policy PasswordPolicy
+	Hasher password.Hasher
+	Logger *log.Logger
+}
+
+// authUserColumns are selected by every query that returns a full AuthUser
+const authUserColumns = `id, login, name, password, created_at, is_deleted, is_service`
+
+// scanAuthUser reads a row selected with authUserColumns
+func scanAuthUser(row interface{ Scan(...interface{}) error }) (*AuthUser, error) {
+	var user AuthUser
+	var isDeleted int
+
+	err := row.Scan(
+		&user.ID,
+		&user.Login,
+		&user.Name,
+		&user.Password,
+		&user.CreatedAt,
+		&isDeleted,
+		&user.IsService,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.IsDeleted = isDeleted != 0
+
+	return &user, nil
 }
 
 // Insert creates a new auth user
-func (m AuthUserModel) Insert(login, password string) (*AuthUser, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+func (m AuthUserModel) Insert(login, password, name string, isService bool) (*AuthUser, error) {
+	hash, err := m.Hasher.Hash(password)
 	if err != nil {
 		return nil, err
 	}
 
 	query := `
-		INSERT INTO system_accounts (login, password)
-		VALUES ($1, $2)
-		RETURNING id, login`
+		INSERT INTO system_accounts (login, password, name, is_service)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + authUserColumns
 
-	args := []interface{}{login, hash}
+	args := []interface{}{login, hash, name, isService}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var user AuthUser
-
-	err = m.DB.QueryRowContext(ctx, query, args...).Scan(
-		&user.ID,
-		&user.Login,
-	)
-
+	user, err := scanAuthUser(m.DB.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "system_accounts_login_key"`:
@@ -60,26 +111,44 @@ func (m AuthUserModel) Insert(login, password string) (*AuthUser, error) {
 		}
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+// Get fetches an active auth user by ID
+func (m AuthUserModel) Get(id int64) (*AuthUser, error) {
+	query := `
+		SELECT ` + authUserColumns + `
+		FROM system_accounts
+		WHERE id = $1 AND is_deleted = 0`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	user, err := scanAuthUser(m.DB.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
 }
 
 // GetByLogin fetches an auth user by login
 func (m AuthUserModel) GetByLogin(login string) (*AuthUser, error) {
 	query := `
-		SELECT id, login, password
+		SELECT ` + authUserColumns + `
 		FROM system_accounts
 		WHERE login = $1 AND is_deleted = 0`
 
-	var user AuthUser
-
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, login).Scan(
-		&user.ID,
-		&user.Login,
-		&user.Password,
-	)
+	user, err := scanAuthUser(m.DB.QueryRowContext(ctx, query, login))
 
 	if err != nil {
 		switch {
@@ -90,31 +159,227 @@ func (m AuthUserModel) GetByLogin(login string) (*AuthUser, error) {
 		}
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // Authenticate verifies login and password
 func (m AuthUserModel) Authenticate(login, password string) (*AuthUser, error) {
 	user, err := m.GetByLogin(login)
 	if err != nil {
-		// Dummy bcrypt для константного времени ответа
-		bcrypt.CompareHashAndPassword(
-			[]byte("$2a$12$000000000000000000000000000000000000000000000000000000"),
-			[]byte(password),
-		)
+		// Dummy hash для константного времени ответа
+		m.Hasher.Hash(password)
 		if errors.Is(err, ErrRecordNotFound) {
 			return nil, ErrInvalidCredentials // Тот же ответ, что и при неверном пароле
 		}
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	match, err := m.Hasher.Verify(user.Password, password)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return nil, ErrInvalidCredentials
-		}
 		return nil, err
 	}
 
+	if !match {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Upgrade hashes made with an old algorithm or cost while the plaintext is at hand
+	// A failed upgrade doesn't block the login, it is retried on the next one
+	if m.Hasher.NeedsRehash(user.Password) {
+		err = m.rehash(user, password)
+		if err != nil && m.Logger != nil {
+			m.Logger.Printf("rehash password of system account %d: %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+// rehash replaces the stored hash unless the password was changed meanwhile
+func (m AuthUserModel) rehash(user *AuthUser, password string) error {
+	hash, err := m.Hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE system_accounts
+		SET password = $1
+		WHERE id = $2 AND password = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, hash, user.ID, user.Password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hash
+
+	return nil
+}
+
+// GetIncludingDeleted fetches an auth user by ID regardless of is_deleted
+func (m AuthUserModel) GetIncludingDeleted(id int64) (*AuthUser, error) {
+	query := `
+		SELECT ` + authUserColumns + `
+		FROM system_accounts
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	user, err := scanAuthUser(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
 	return user, nil
 }
+
+// GetAll lists system accounts ordered by ID
+func (m AuthUserModel) GetAll(includeDeleted bool) ([]*AuthUser, error) {
+	query := `
+		SELECT ` + authUserColumns + `
+		FROM system_accounts
+		WHERE is_deleted = 0 OR $1
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*AuthUser{}
+
+	for rows.Next() {
+		user, err := scanAuthUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Update changes login, name and the service flag of an active auth user
+func (m AuthUserModel) Update(user *AuthUser) error {
+	query := `
+		UPDATE system_accounts
+		SET login = $1, name = $2, is_service = $3
+		WHERE id = $4 AND is_deleted = 0`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, user.Login, user.Name, user.IsService, user.ID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "system_accounts_login_key"`:
+			return ErrDuplicateLogin
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SoftDelete marks an auth user as deleted; deleted users can't log in
+func (m AuthUserModel) SoftDelete(id int64) error {
+	return m.setDeleted(id, 1)
+}
+
+// Restore clears the deleted flag
+// Returns ErrDuplicateLogin if the login was taken while the user was deleted
+func (m AuthUserModel) Restore(id int64) error {
+	return m.setDeleted(id, 0)
+}
+
+func (m AuthUserModel) setDeleted(id int64, isDeleted int) error {
+	query := `
+		UPDATE system_accounts
+		SET is_deleted = $2
+		WHERE id = $1 AND is_deleted <> $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, isDeleted)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "system_accounts_login_key"`:
+			return ErrDuplicateLogin
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SetPassword replaces the password of an active auth user
+func (m AuthUserModel) SetPassword(id int64, password string) error {
+	hash, err := m.Hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE system_accounts
+		SET password = $1
+		WHERE id = $2 AND is_deleted = 0`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, hash, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
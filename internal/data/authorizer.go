@@ -0,0 +1,208 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// DefaultAuthorizerTTL bounds how long a cached FID set is trusted
+// when a change notification gets lost
+const DefaultAuthorizerTTL = time.Minute
+
+// Authorizer decides whether a system account may use a FID
+type Authorizer interface {
+	HasPermission(userID, fid int64) (bool, error)
+	// Scope returns which accounts the user may reach with fid; nil without the permission
+	Scope(userID, fid int64) (*AccessScope, error)
+	// Grants returns every FID the user holds with its scope; the map must not be modified
+	Grants(userID int64) (map[int64]*AccessScope, error)
+	// Invalidate drops cached permissions of one system account
+	Invalidate(userID int64)
+	// InvalidateAll drops every cached permission set
+	InvalidateAll()
+}
+
+// CachedAuthorizer keeps each user's FID set in memory for TTL
+// Rights and membership changes are pushed through Postgres NOTIFY
+// and applied with Invalidate/InvalidateAll; a membership window opening
+// or closing sends nothing, so an entry never outlives the next boundary
+type CachedAuthorizer struct {
+	DB  *sql.DB
+	TTL time.Duration
+
+	mu         sync.RWMutex
+	entries    map[int64]authzEntry
+	generation uint64 // Bumped on every invalidation
+}
+
+type authzEntry struct {
+	grants    map[int64]*AccessScope // fid -> union of the user's grants
+	expiresAt time.Time
+}
+
+// NewCachedAuthorizer creates an authorizer with an empty cache
+func NewCachedAuthorizer(db *sql.DB, ttl time.Duration) *CachedAuthorizer {
+	return &CachedAuthorizer{
+		DB:      db,
+		TTL:     ttl,
+		entries: make(map[int64]authzEntry),
+	}
+}
+
+// HasPermission reports whether the user has fid through any of their groups
+func (a *CachedAuthorizer) HasPermission(userID, fid int64) (bool, error) {
+	grants, err := a.get(userID)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := grants[fid]
+
+	return ok, nil
+}
+
+func (a *CachedAuthorizer) Scope(userID, fid int64) (*AccessScope, error) {
+	grants, err := a.get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return grants[fid], nil
+}
+
+func (a *CachedAuthorizer) Grants(userID int64) (map[int64]*AccessScope, error) {
+	return a.get(userID)
+}
+
+// get returns the cached grants of a user, loading them when missing or expired
+func (a *CachedAuthorizer) get(userID int64) (map[int64]*AccessScope, error) {
+	a.mu.RLock()
+	entry, ok := a.entries[userID]
+	generation := a.generation
+	a.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.grants, nil
+	}
+
+	grants, err := a.load(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(a.TTL)
+
+	boundary, err := a.nextBoundary(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if boundary != nil && boundary.Before(expiresAt) {
+		expiresAt = *boundary
+	}
+
+	a.mu.Lock()
+	// A result loaded before an invalidation may already be stale, so it isn't cached
+	if a.generation == generation {
+		a.entries[userID] = authzEntry{grants: grants, expiresAt: expiresAt}
+	}
+	a.mu.Unlock()
+
+	return grants, nil
+}
+
+func (a *CachedAuthorizer) Invalidate(userID int64) {
+	a.mu.Lock()
+	delete(a.entries, userID)
+	a.generation++
+	a.mu.Unlock()
+}
+
+func (a *CachedAuthorizer) InvalidateAll() {
+	a.mu.Lock()
+	a.entries = make(map[int64]authzEntry)
+	a.generation++
+	a.mu.Unlock()
+}
+
+// load reads the user's grants with their scopes from the database
+// A grant without scope rows comes back as one row with NULL scope columns
+func (a *CachedAuthorizer) load(userID int64) (map[int64]*AccessScope, error) {
+	query := `
+		SELECT sr.fid, sc.kind, sc.account_id_from, sc.account_id_to, COALESCE(sc.value, '')
+		FROM system_groups sg
+		INNER JOIN system_rights sr ON sr.group_id = sg.group_id
+		LEFT JOIN system_right_scopes sc ON sc.group_id = sr.group_id AND sc.fid = sr.fid
+		WHERE sg.user_id = $1
+		  AND ` + activeMembership
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := a.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := make(map[int64]*AccessScope)
+
+	for rows.Next() {
+		var fid int64
+		var kind sql.NullString
+		var scope Scope
+
+		err := rows.Scan(&fid, &kind, &scope.From, &scope.To, &scope.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		access, ok := grants[fid]
+		if !ok {
+			access = &AccessScope{}
+			grants[fid] = access
+		}
+
+		if !kind.Valid {
+			access.Unrestricted = true
+			continue
+		}
+
+		scope.Kind = kind.String
+		access.Scopes = append(access.Scopes, scope)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+// nextBoundary returns when one of the user's memberships starts or ends next, nil if never
+func (a *CachedAuthorizer) nextBoundary(userID int64) (*time.Time, error) {
+	query := `
+		SELECT MIN(w.boundary)
+		FROM system_groups sg
+		CROSS JOIN LATERAL (VALUES (sg.valid_from), (sg.valid_until)) AS w(boundary)
+		WHERE sg.user_id = $1
+		  AND w.boundary > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var boundary sql.NullTime
+
+	err := a.DB.QueryRowContext(ctx, query, userID).Scan(&boundary)
+	if err != nil {
+		return nil, err
+	}
+
+	if !boundary.Valid {
+		return nil, nil
+	}
+
+	return &boundary.Time, nil
+}
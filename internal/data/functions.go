@@ -0,0 +1,174 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Категории функций для группировки в интерфейсе
+const (
+	CategoryAccounts = "accounts"
+	CategoryTariffs  = "tariffs"
+	CategoryBilling  = "billing"
+	CategoryAuth     = "auth"
+	CategoryAdmin    = "admin"
+)
+
+// Function описывает FID: что разрешает право с этим идентификатором
+type Function struct {
+	FID         int64  `json:"fid"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+// FunctionRegistry — реестр всех FID, известных API
+// При запуске синхронизируется в таблицу system_functions;
+// новый FID добавляется сюда вместе с константой
+var FunctionRegistry = []Function{
+	{FIDAccountsRead, "accounts.read", "Чтение аккаунтов", "Просмотр аккаунтов бизнес-пользователей", CategoryAccounts},
+	{FIDTariffsRead, "tariffs.read", "Чтение тарифов", "Просмотр каталога тарифов, тарифа аккаунта, его истории и запланированных смен", CategoryTariffs},
+	{FIDTariffsUpdate, "tariffs.update", "Обновление тарифов", "Смена тарифа аккаунта, планирование и отмена смены", CategoryTariffs},
+	{FIDTariffCatalogCreate, "tariff_catalog.create", "Создание тарифов в каталоге", "Добавление нового тарифа в каталог", CategoryTariffs},
+	{FIDTariffCatalogUpdate, "tariff_catalog.update", "Редактирование тарифов в каталоге", "Изменение названия, описания, цены и активности тарифа", CategoryTariffs},
+	{FIDTariffCatalogDelete, "tariff_catalog.delete", "Удаление тарифов из каталога", "Удаление тарифа, не назначенного ни одному аккаунту", CategoryTariffs},
+	{FIDInvoicesRead, "invoices.read", "Просмотр счетов на оплату", "Просмотр счетов аккаунта и строк счёта", CategoryBilling},
+	{FIDLedgerRead, "ledger.read", "Просмотр баланса и проводок", "Просмотр баланса аккаунта и истории проводок", CategoryBilling},
+	{FIDPaymentsCreate, "payments.create", "Внесение платежей", "Запись входящего платежа и его распределение по счетам", CategoryBilling},
+	{FIDTokensRevoke, "tokens.revoke", "Отзыв токенов", "Отзыв отдельного токена или всех токенов системного пользователя", CategoryAuth},
+	{FIDLoginUnlock, "login.unlock", "Снятие блокировки входа", "Снятие блокировки после неудачных попыток входа", CategoryAuth},
+	{FIDSystemAccountsRead, "system_accounts.read", "Просмотр системных пользователей", "Список и карточки системных пользователей, в том числе удалённых", CategoryAdmin},
+	{FIDSystemAccountsCreate, "system_accounts.create", "Создание системных пользователей", "Заведение нового оператора", CategoryAdmin},
+	{FIDSystemAccountsUpdate, "system_accounts.update", "Редактирование системных пользователей", "Изменение логина и имени оператора", CategoryAdmin},
+	{FIDSystemAccountsDelete, "system_accounts.delete", "Удаление и восстановление системных пользователей", "Мягкое удаление оператора и его восстановление", CategoryAdmin},
+	{FIDPasswordReset, "password.reset", "Выдача токена сброса пароля", "Выдача одноразового токена для установки нового пароля", CategoryAuth},
+	{FIDGroupsRead, "groups.read", "Просмотр групп", "Просмотр групп, их прав и участников, справочник FID", CategoryAdmin},
+	{FIDGroupsCreate, "groups.create", "Создание групп", "Создание новой группы", CategoryAdmin},
+	{FIDGroupsUpdate, "groups.update", "Переименование групп", "Изменение названия, описания и требования 2FA группы", CategoryAdmin},
+	{FIDGroupsDelete, "groups.delete", "Удаление групп", "Удаление группы вместе с её правами и членствами", CategoryAdmin},
+	{FIDGroupRightsGrant, "group_rights.grant", "Выдача прав группе", "Выдача FID группе", CategoryAdmin},
+	{FIDGroupRightsRevoke, "group_rights.revoke", "Отзыв прав у группы", "Отзыв FID у группы", CategoryAdmin},
+	{FIDGroupMembersAdd, "group_members.add", "Добавление участников в группу", "Добавление системного пользователя в группу", CategoryAdmin},
+	{FIDGroupMembersRemove, "group_members.remove", "Удаление участников из группы", "Удаление системного пользователя из группы", CategoryAdmin},
+	{FIDPermissionsExplain, "permissions.explain", "Разбор проверки прав", "Почему системному пользователю разрешён или запрещён FID: группы и членства", CategoryAdmin},
+	{FIDAPIKeysRead, "api_keys.read", "Просмотр API-ключей", "Список API-ключей системного пользователя, без самих ключей", CategoryAuth},
+	{FIDAPIKeysCreate, "api_keys.create", "Выпуск API-ключей", "Выпуск API-ключа для межсервисного клиента, при необходимости с ограниченным набором FID и сроком действия", CategoryAuth},
+	{FIDAPIKeysRevoke, "api_keys.revoke", "Отзыв API-ключей", "Отзыв API-ключа системного пользователя", CategoryAuth},
+	{FIDImpersonate, "impersonate", "Вход от имени системного пользователя", "Выдача токена, действующего с правами другого пользователя, права которого не шире собственных; действия записываются на выдавшего", CategoryAuth},
+	{FIDUsersRead, "users.read", "Просмотр пользователей", "Список пользователей с поиском по имени, телефону и email, карточка пользователя", CategoryAccounts},
+	{FIDUsersCreate, "users.create", "Создание пользователей", "Заведение бизнес-пользователя", CategoryAccounts},
+	{FIDUsersUpdate, "users.update", "Редактирование пользователей", "Изменение имени, контактов и документов пользователя", CategoryAccounts},
+	{FIDUsersDelete, "users.delete", "Удаление пользователей", "Удаление пользователя, у которого нет аккаунтов", CategoryAccounts},
+	{FIDAccountsCreate, "accounts.create", "Создание аккаунтов", "Заведение аккаунта пользователю с регионом и метками", CategoryAccounts},
+	{FIDAccountsUpdate, "accounts.update", "Редактирование аккаунтов", "Изменение региона и меток аккаунта", CategoryAccounts},
+}
+
+// FunctionCode возвращает код FID из реестра или пустую строку для неизвестного FID
+func FunctionCode(fid int64) string {
+	for _, f := range FunctionRegistry {
+		if f.FID == fid {
+			return f.Code
+		}
+	}
+
+	return ""
+}
+
+// FunctionModel обрабатывает операции со справочником FID
+type FunctionModel struct {
+	DB *sql.DB
+}
+
+// Sync записывает реестр в system_functions, обновляя описания существующих FID
+// FID, которых нет в реестре, не удаляются: на них могут ссылаться выданные права
+func (m FunctionModel) Sync(registry []Function) error {
+	query := `
+		INSERT INTO system_functions (fid, code, name, description, category)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (fid) DO UPDATE
+		SET code = EXCLUDED.code,
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			category = EXCLUDED.category,
+			updated_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, f := range registry {
+		_, err = tx.ExecContext(ctx, query, f.FID, f.Code, f.Name, f.Description, f.Category)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get возвращает FID из справочника
+func (m FunctionModel) Get(fid int64) (*Function, error) {
+	query := `
+		SELECT fid, code, name, description, category
+		FROM system_functions
+		WHERE fid = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var f Function
+
+	err := m.DB.QueryRowContext(ctx, query, fid).Scan(&f.FID, &f.Code, &f.Name, &f.Description, &f.Category)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &f, nil
+}
+
+// GetAll возвращает все FID из справочника
+func (m FunctionModel) GetAll() ([]Function, error) {
+	query := `
+		SELECT fid, code, name, description, category
+		FROM system_functions
+		ORDER BY fid`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	functions := []Function{}
+
+	for rows.Next() {
+		var f Function
+		err := rows.Scan(&f.FID, &f.Code, &f.Name, &f.Description, &f.Category)
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return functions, nil
+}
@@ -3,57 +3,90 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
+
+	"biling_api/internal/validator"
+)
+
+var (
+	ErrDuplicateGroupName = errors.New("duplicate group name")
+	ErrUnknownFunction    = errors.New("unknown function id")
+	ErrProtectedGroup     = errors.New("protected group")
+	ErrLastGroupMember    = errors.New("last group member")
 )
 
+// AdminGroupID is the Administrators group created by the migrations
+// It can't be deleted or left without active members, or nobody could manage access
+const AdminGroupID int64 = 1
+
 // Group represents an access control group
 type Group struct {
 	ID          int64  `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	RequireMFA  bool   `json:"require_mfa"`
 }
 
-// Permission represents a group permission (FID from system_rights)
-type Permission struct {
-	FID int64 `json:"fid"` // Feature ID from system_rights
+// activeMembership limits system_groups (aliased sg) to memberships valid right now
+const activeMembership = `(sg.valid_from IS NULL OR sg.valid_from <= NOW())
+		  AND (sg.valid_until IS NULL OR sg.valid_until > NOW())`
+
+// GroupMember is a system account that belongs to a group
+// A membership without ValidFrom/ValidUntil is permanent
+type GroupMember struct {
+	UserID     int64      `json:"user_id"`
+	Login      string     `json:"login"`
+	Name       string     `json:"name"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	GrantedBy  *int64     `json:"granted_by"`
+	GrantedAt  time.Time  `json:"granted_at"`
 }
 
-// GroupModel wraps database connection
-type GroupModel struct {
-	DB *sql.DB
+// ValidateMembershipWindow checks the optional validity window of a membership
+func ValidateMembershipWindow(v *validator.Validator, validFrom, validUntil *time.Time) {
+	if validUntil != nil {
+		v.Check(validUntil.After(time.Now()), "valid_until", "must be in the future")
+
+		if validFrom != nil {
+			v.Check(validUntil.After(*validFrom), "valid_until", "must be after valid_from")
+		}
+	}
 }
 
-// HasPermission checks if a user has a specific permission (fid) through their groups
-// Uses system_rights table with fid (feature ID)
-func (m GroupModel) HasPermission(authUserID int64, fid int64) (bool, error) {
-	query := `
-		SELECT COUNT(*)
-		FROM system_rights sr
-		INNER JOIN system_groups sg ON sg.group_id = sr.group_id
-		WHERE sg.user_id = $1
-		  AND sr.fid = $2
-		  AND sg.user_id > 0`
+// ValidateGroup checks group fields before they are written
+func ValidateGroup(v *validator.Validator, group *Group) {
+	v.Check(group.Name != "", "name", "must be provided")
+	v.Check(len(group.Name) <= 255, "name", "must not be more than 255 bytes long")
+	v.Check(len(group.Description) <= 255, "description", "must not be more than 255 bytes long")
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// Permission represents a group permission (FID from system_rights)
+type Permission struct {
+	FID  int64  `json:"fid"`  // Feature ID from system_rights
+	Code string `json:"code"` // Stable code from system_functions
+	Name string `json:"name"` // Human-readable name for the UI
 
-	var count int
-	err := m.DB.QueryRowContext(ctx, query, authUserID, fid).Scan(&count)
-	if err != nil {
-		return false, err
-	}
+	// Scopes restrict a group grant to part of the accounts; empty means all accounts
+	Scopes []Scope `json:"scopes,omitempty"`
+}
 
-	return count > 0, nil
+// GroupModel wraps database connection
+type GroupModel struct {
+	DB *sql.DB
 }
 
 // GetUserPermissions fetches all permissions (fids) for a user through their groups
 func (m GroupModel) GetUserPermissions(authUserID int64) ([]Permission, error) {
 	query := `
-		SELECT DISTINCT sr.fid
+		SELECT DISTINCT sr.fid, sf.code, sf.name
 		FROM system_rights sr
 		INNER JOIN system_groups sg ON sg.group_id = sr.group_id
+		INNER JOIN system_functions sf ON sf.fid = sr.fid
 		WHERE sg.user_id = $1
 		  AND sg.user_id > 0
+		  AND ` + activeMembership + `
 		ORDER BY sr.fid`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -69,7 +102,7 @@ func (m GroupModel) GetUserPermissions(authUserID int64) ([]Permission, error) {
 
 	for rows.Next() {
 		var p Permission
-		err := rows.Scan(&p.FID)
+		err := rows.Scan(&p.FID, &p.Code, &p.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -86,11 +119,12 @@ func (m GroupModel) GetUserPermissions(authUserID int64) ([]Permission, error) {
 // GetUserGroups fetches all groups that a user belongs to
 func (m GroupModel) GetUserGroups(authUserID int64) ([]Group, error) {
 	query := `
-		SELECT sgi.id, sgi.name, sgi.description
+		SELECT sgi.id, sgi.name, sgi.description, sgi.require_mfa
 		FROM system_group_info sgi
 		INNER JOIN system_groups sg ON sg.group_id = sgi.id
 		WHERE sg.user_id = $1
 		  AND sg.user_id > 0
+		  AND ` + activeMembership + `
 		ORDER BY sgi.id`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -106,7 +140,7 @@ func (m GroupModel) GetUserGroups(authUserID int64) ([]Group, error) {
 
 	for rows.Next() {
 		var g Group
-		err := rows.Scan(&g.ID, &g.Name, &g.Description)
+		err := rows.Scan(&g.ID, &g.Name, &g.Description, &g.RequireMFA)
 		if err != nil {
 			return nil, err
 		}
@@ -119,3 +153,566 @@ func (m GroupModel) GetUserGroups(authUserID int64) ([]Group, error) {
 
 	return groups, nil
 }
+
+// Insert creates a new group
+func (m GroupModel) Insert(group *Group) error {
+	query := `
+		INSERT INTO system_group_info (name, description, require_mfa)
+		VALUES ($1, $2, $3)
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, group.Name, group.Description, group.RequireMFA).Scan(&group.ID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "system_group_info_name_key"`:
+			return ErrDuplicateGroupName
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get fetches a group by ID
+func (m GroupModel) Get(id int64) (*Group, error) {
+	query := `
+		SELECT id, name, description, require_mfa
+		FROM system_group_info
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var g Group
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&g.ID, &g.Name, &g.Description, &g.RequireMFA)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &g, nil
+}
+
+// GetAll lists all groups ordered by ID
+func (m GroupModel) GetAll() ([]Group, error) {
+	query := `
+		SELECT id, name, description, require_mfa
+		FROM system_group_info
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []Group{}
+
+	for rows.Next() {
+		var g Group
+		err := rows.Scan(&g.ID, &g.Name, &g.Description, &g.RequireMFA)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// Update changes name, description and the MFA requirement of a group
+func (m GroupModel) Update(group *Group) error {
+	query := `
+		UPDATE system_group_info
+		SET name = $1, description = $2, require_mfa = $3
+		WHERE id = $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, group.Name, group.Description, group.RequireMFA, group.ID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "system_group_info_name_key"`:
+			return ErrDuplicateGroupName
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a group; its rights and memberships are removed by ON DELETE CASCADE
+// Returns ErrProtectedGroup for the admin group
+func (m GroupModel) Delete(id int64) error {
+	if id == AdminGroupID {
+		return ErrProtectedGroup
+	}
+
+	query := `
+		DELETE FROM system_group_info
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetRights returns the FIDs granted to a group with their scopes
+func (m GroupModel) GetRights(groupID int64) ([]Permission, error) {
+	query := `
+		SELECT sr.fid, sf.code, sf.name, sc.kind, sc.account_id_from, sc.account_id_to, COALESCE(sc.value, '')
+		FROM system_rights sr
+		INNER JOIN system_functions sf ON sf.fid = sr.fid
+		LEFT JOIN system_right_scopes sc ON sc.group_id = sr.group_id AND sc.fid = sr.fid
+		WHERE sr.group_id = $1
+		ORDER BY sr.fid, sc.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rights := []Permission{}
+
+	for rows.Next() {
+		var p Permission
+		var kind sql.NullString
+		var scope Scope
+
+		err := rows.Scan(&p.FID, &p.Code, &p.Name, &kind, &scope.From, &scope.To, &scope.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rows of one FID come one after another
+		if len(rights) == 0 || rights[len(rights)-1].FID != p.FID {
+			rights = append(rights, p)
+		}
+
+		if kind.Valid {
+			scope.Kind = kind.String
+			last := &rights[len(rights)-1]
+			last.Scopes = append(last.Scopes, scope)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rights, nil
+}
+
+// Membership is a system account's row in system_groups
+type Membership struct {
+	ID         int64      `json:"id"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	GrantedBy  *int64     `json:"granted_by"`
+	GrantedAt  time.Time  `json:"granted_at"`
+	Active     bool       `json:"active"` // Inside the validity window right now
+}
+
+// PermissionGrant is a group that grants a FID together with one user's membership in it
+type PermissionGrant struct {
+	GroupID    int64       `json:"group_id"`
+	GroupName  string      `json:"group_name"`
+	Scopes     []Scope     `json:"scopes"`     // Empty: the grant covers every account
+	Membership *Membership `json:"membership"` // nil: the user isn't a member
+}
+
+// GetGrants returns every group that grants fid, with the user's membership in each
+func (m GroupModel) GetGrants(fid, userID int64) ([]PermissionGrant, error) {
+	query := `
+		SELECT sgi.id, sgi.name,
+			sg.id, sg.valid_from, sg.valid_until, sg.granted_by, sg.granted_at,
+			sg.id IS NOT NULL AND ` + activeMembership + `,
+			sc.kind, sc.account_id_from, sc.account_id_to, COALESCE(sc.value, '')
+		FROM system_rights sr
+		INNER JOIN system_group_info sgi ON sgi.id = sr.group_id
+		LEFT JOIN system_groups sg ON sg.group_id = sr.group_id AND sg.user_id = $2
+		LEFT JOIN system_right_scopes sc ON sc.group_id = sr.group_id AND sc.fid = sr.fid
+		WHERE sr.fid = $1
+		ORDER BY sgi.id, sc.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, fid, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []PermissionGrant{}
+
+	for rows.Next() {
+		var g PermissionGrant
+		var membershipID sql.NullInt64
+		var grantedAt sql.NullTime
+		var ms Membership
+		var kind sql.NullString
+		var scope Scope
+
+		err := rows.Scan(
+			&g.GroupID,
+			&g.GroupName,
+			&membershipID,
+			&ms.ValidFrom,
+			&ms.ValidUntil,
+			&ms.GrantedBy,
+			&grantedAt,
+			&ms.Active,
+			&kind,
+			&scope.From,
+			&scope.To,
+			&scope.Value,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rows of one group come one after another
+		if len(grants) == 0 || grants[len(grants)-1].GroupID != g.GroupID {
+			g.Scopes = []Scope{}
+			if membershipID.Valid {
+				ms.ID = membershipID.Int64
+				ms.GrantedAt = grantedAt.Time
+				g.Membership = &ms
+			}
+			grants = append(grants, g)
+		}
+
+		if kind.Valid {
+			scope.Kind = kind.String
+			last := &grants[len(grants)-1]
+			last.Scopes = append(last.Scopes, scope)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+// GrantRight gives a FID to a group and replaces the scopes of the grant
+// Without scopes the grant covers every account
+// Returns ErrUnknownFunction for FIDs missing from system_functions
+func (m GroupModel) GrantRight(groupID, fid int64, scopes []Scope) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO system_rights (group_id, fid)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, groupID, fid)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "system_rights" violates foreign key constraint "system_rights_group_id_fkey"`:
+			return ErrRecordNotFound
+		case err.Error() == `pq: insert or update on table "system_rights" violates foreign key constraint "system_rights_fid_fkey"`:
+			return ErrUnknownFunction
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM system_right_scopes
+		WHERE group_id = $1 AND fid = $2`, groupID, fid)
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range scopes {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO system_right_scopes (group_id, fid, kind, account_id_from, account_id_to, value)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))`,
+			groupID, fid, scope.Kind, scope.From, scope.To, scope.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RevokeRight takes a FID away from a group
+func (m GroupModel) RevokeRight(groupID, fid int64) error {
+	query := `
+		DELETE FROM system_rights
+		WHERE group_id = $1 AND fid = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, groupID, fid)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetMembers returns system accounts that belong to a group, including not yet valid memberships
+func (m GroupModel) GetMembers(groupID int64) ([]GroupMember, error) {
+	query := `
+		SELECT sa.id, sa.login, sa.name, sg.valid_from, sg.valid_until, sg.granted_by, sg.granted_at
+		FROM system_groups sg
+		INNER JOIN system_accounts sa ON sa.id = sg.user_id
+		WHERE sg.group_id = $1
+		ORDER BY sa.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []GroupMember{}
+
+	for rows.Next() {
+		var gm GroupMember
+		err := rows.Scan(&gm.UserID, &gm.Login, &gm.Name, &gm.ValidFrom, &gm.ValidUntil, &gm.GrantedBy, &gm.GrantedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, gm)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// AddMember puts a system account into a group for the given window and records it in the audit trail
+// For an existing member the window and grantor are replaced
+func (m GroupModel) AddMember(groupID, userID int64, validFrom, validUntil *time.Time, grantedBy int64) error {
+	query := `
+		WITH added AS (
+			INSERT INTO system_groups (group_id, user_id, valid_from, valid_until, granted_by)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (group_id, user_id) DO UPDATE
+			SET valid_from = EXCLUDED.valid_from,
+				valid_until = EXCLUDED.valid_until,
+				granted_by = EXCLUDED.granted_by,
+				granted_at = NOW()
+			RETURNING id, group_id, user_id, valid_from, valid_until
+		)
+		INSERT INTO audit_log (actor_id, action, entity, entity_id, details)
+		SELECT $5, $6, $7, id,
+			jsonb_build_object(
+				'group_id', group_id,
+				'user_id', user_id,
+				'valid_from', valid_from,
+				'valid_until', valid_until
+			)
+		FROM added`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{groupID, userID, validFrom, validUntil, grantedBy, AuditMembershipAdded, AuditEntityMembership}
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "system_groups" violates foreign key constraint "system_groups_group_id_fkey"`,
+			err.Error() == `pq: insert or update on table "system_groups" violates foreign key constraint "system_groups_user_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveMember removes a system account from a group and records it in the audit trail
+// Returns ErrLastGroupMember if the admin group would be left without active members
+func (m GroupModel) RemoveMember(groupID, userID, removedBy int64) error {
+	query := `
+		WITH removed AS (
+			DELETE FROM system_groups
+			WHERE group_id = $1 AND user_id = $2
+			RETURNING id, group_id, user_id, valid_from, valid_until, granted_by
+		)
+		INSERT INTO audit_log (actor_id, action, entity, entity_id, details)
+		SELECT $3, $4, $5, id,
+			jsonb_build_object(
+				'group_id', group_id,
+				'user_id', user_id,
+				'valid_from', valid_from,
+				'valid_until', valid_until,
+				'granted_by', granted_by
+			)
+		FROM removed`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Removals from the admin group wait for each other, so two of them can't both leave it empty
+	if groupID == AdminGroupID {
+		_, err = tx.ExecContext(ctx, `SELECT id FROM system_group_info WHERE id = $1 FOR UPDATE`, groupID)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, query, groupID, userID, removedBy, AuditMembershipRemoved, AuditEntityMembership)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	if groupID == AdminGroupID {
+		countQuery := `
+			SELECT COUNT(*)
+			FROM system_groups sg
+			INNER JOIN system_accounts sa ON sa.id = sg.user_id
+			WHERE sg.group_id = $1 AND sa.is_deleted = 0
+			  AND ` + activeMembership
+
+		var remaining int
+
+		err = tx.QueryRowContext(ctx, countQuery, groupID).Scan(&remaining)
+		if err != nil {
+			return err
+		}
+
+		if remaining == 0 {
+			return ErrLastGroupMember
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpiredMemberships removes memberships whose window has ended
+// and writes an audit entry for each one in the same statement
+func (m GroupModel) DeleteExpiredMemberships() ([]AuditEntry, error) {
+	query := `
+		WITH removed AS (
+			DELETE FROM system_groups
+			WHERE valid_until <= NOW()
+			RETURNING id, group_id, user_id, valid_from, valid_until, granted_by
+		)
+		INSERT INTO audit_log (actor_id, action, entity, entity_id, details)
+		SELECT NULL, $1, $2, id,
+			jsonb_build_object(
+				'group_id', group_id,
+				'user_id', user_id,
+				'valid_from', valid_from,
+				'valid_until', valid_until,
+				'granted_by', granted_by
+			)
+		FROM removed
+		RETURNING ` + auditEntryColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, AuditMembershipExpired, AuditEntityMembership)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
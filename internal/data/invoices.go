@@ -0,0 +1,376 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrDuplicateInvoice = errors.New("duplicate invoice")
+)
+
+// Invoice statuses
+const (
+	InvoiceOpen = "open"
+	InvoicePaid = "paid"
+)
+
+// Invoice is a bill for one account and one billing period
+type Invoice struct {
+	ID          int64          `json:"id"`
+	AccountID   int64          `json:"account_id"`
+	PeriodStart time.Time      `json:"period_start"`
+	PeriodEnd   time.Time      `json:"period_end"`
+	Total       Money          `json:"total"`
+	Currency    string         `json:"currency"`
+	Status      string         `json:"status"`
+	IssuedAt    time.Time      `json:"issued_at"`
+	Lines       []*InvoiceLine `json:"lines,omitempty"`
+}
+
+// InvoiceLine is a single charge on an invoice
+type InvoiceLine struct {
+	ID          int64  `json:"id"`
+	InvoiceID   int64  `json:"invoice_id"`
+	TariffID    *int64 `json:"tariff_id,omitempty"`
+	Description string `json:"description"`
+	Quantity    int64  `json:"quantity"`
+	UnitPrice   Money  `json:"unit_price"`
+	Amount      Money  `json:"amount"`
+}
+
+// BillableAccount is an account with its assigned tariff that still needs an invoice
+type BillableAccount struct {
+	AccountID  int64
+	TariffID   int64
+	TariffName string
+	Price      Money
+}
+
+// InvoiceModel handles database operations for invoices
+type InvoiceModel struct {
+	DB *sql.DB
+}
+
+// Insert stores an invoice with its lines and its ledger posting in one transaction
+// Credit left on the account by earlier payments is applied to it in the same transaction
+// The posting may be nil for zero-total invoices
+// Returns ErrDuplicateInvoice if the account is already invoiced for the period
+func (m InvoiceModel) Insert(invoice *Invoice, posting *LedgerTransaction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO invoices (account_id, period_start, period_end, total, currency)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id, period_start) DO NOTHING
+		RETURNING id, status, issued_at`
+
+	args := []interface{}{
+		invoice.AccountID,
+		invoice.PeriodStart,
+		invoice.PeriodEnd,
+		invoice.Total,
+		invoice.Currency,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&invoice.ID, &invoice.Status, &invoice.IssuedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDuplicateInvoice
+		default:
+			return err
+		}
+	}
+
+	lineQuery := `
+		INSERT INTO invoice_lines (invoice_id, tariff_id, description, quantity, unit_price, amount)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	for _, line := range invoice.Lines {
+		line.InvoiceID = invoice.ID
+
+		err = tx.QueryRowContext(ctx, lineQuery,
+			line.InvoiceID,
+			line.TariffID,
+			line.Description,
+			line.Quantity,
+			line.UnitPrice,
+			line.Amount,
+		).Scan(&line.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if posting != nil {
+		for _, entry := range posting.Entries {
+			entry.InvoiceID = &invoice.ID
+		}
+
+		err = postLedgerTx(ctx, tx, posting)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = applyCreditTx(ctx, tx, invoice)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// applyCreditTx pays the new invoice from payments that were left unallocated, oldest first
+func applyCreditTx(ctx context.Context, tx *sql.Tx, invoice *Invoice) error {
+	creditQuery := `
+		SELECT p.id, p.amount - COALESCE((
+			SELECT SUM(pa.amount) FROM payment_allocations pa WHERE pa.payment_id = p.id
+		), 0)
+		FROM payments p
+		WHERE p.account_id = $1 AND p.currency = $2
+		ORDER BY p.created_at, p.id
+		FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, creditQuery, invoice.AccountID, invoice.Currency)
+	if err != nil {
+		return err
+	}
+
+	type credit struct {
+		paymentID   int64
+		unallocated Money
+	}
+
+	var credits []credit
+
+	for rows.Next() {
+		var c credit
+		if err := rows.Scan(&c.paymentID, &c.unallocated); err != nil {
+			rows.Close()
+			return err
+		}
+		if c.unallocated > 0 {
+			credits = append(credits, c)
+		}
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	allocationQuery := `
+		INSERT INTO payment_allocations (payment_id, invoice_id, amount)
+		VALUES ($1, $2, $3)`
+
+	outstanding := invoice.Total
+
+	for _, c := range credits {
+		if outstanding <= 0 {
+			break
+		}
+
+		amount := c.unallocated
+		if amount > outstanding {
+			amount = outstanding
+		}
+
+		_, err = tx.ExecContext(ctx, allocationQuery, c.paymentID, invoice.ID, amount)
+		if err != nil {
+			return err
+		}
+
+		outstanding -= amount
+	}
+
+	if invoice.Total > 0 && outstanding == 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE invoices SET status = 'paid' WHERE id = $1`, invoice.ID)
+		if err != nil {
+			return err
+		}
+		invoice.Status = InvoicePaid
+	}
+
+	return nil
+}
+
+// Get fetches an invoice with its lines
+func (m InvoiceModel) Get(id int64) (*Invoice, error) {
+	query := `
+		SELECT id, account_id, period_start, period_end, total, currency, status, issued_at
+		FROM invoices
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var invoice Invoice
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&invoice.ID,
+		&invoice.AccountID,
+		&invoice.PeriodStart,
+		&invoice.PeriodEnd,
+		&invoice.Total,
+		&invoice.Currency,
+		&invoice.Status,
+		&invoice.IssuedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	linesQuery := `
+		SELECT id, invoice_id, tariff_id, description, quantity, unit_price, amount
+		FROM invoice_lines
+		WHERE invoice_id = $1
+		ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, linesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoice.Lines = []*InvoiceLine{}
+
+	for rows.Next() {
+		var line InvoiceLine
+		var tariffID sql.NullInt64
+
+		err := rows.Scan(
+			&line.ID,
+			&line.InvoiceID,
+			&tariffID,
+			&line.Description,
+			&line.Quantity,
+			&line.UnitPrice,
+			&line.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if tariffID.Valid {
+			line.TariffID = &tariffID.Int64
+		}
+
+		invoice.Lines = append(invoice.Lines, &line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+// GetByAccountID lists invoices of an account, newest period first (without lines)
+func (m InvoiceModel) GetByAccountID(accountID int64) ([]*Invoice, error) {
+	query := `
+		SELECT id, account_id, period_start, period_end, total, currency, status, issued_at
+		FROM invoices
+		WHERE account_id = $1
+		ORDER BY period_start DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []*Invoice{}
+
+	for rows.Next() {
+		var invoice Invoice
+
+		err := rows.Scan(
+			&invoice.ID,
+			&invoice.AccountID,
+			&invoice.PeriodStart,
+			&invoice.PeriodEnd,
+			&invoice.Total,
+			&invoice.Currency,
+			&invoice.Status,
+			&invoice.IssuedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		invoices = append(invoices, &invoice)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invoices, nil
+}
+
+// GetBillableAccounts returns accounts with a tariff that have no invoice for the period yet
+func (m InvoiceModel) GetBillableAccounts(periodStart time.Time) ([]*BillableAccount, error) {
+	query := `
+		SELECT atl.account_id, t.id, t.name, t.price
+		FROM account_tariff_link atl
+		INNER JOIN tariffs t ON t.id = atl.tariff_id
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM invoices i
+			WHERE i.account_id = atl.account_id AND i.period_start = $1
+		)
+		ORDER BY atl.account_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, periodStart)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*BillableAccount{}
+
+	for rows.Next() {
+		var account BillableAccount
+
+		err := rows.Scan(
+			&account.AccountID,
+			&account.TariffID,
+			&account.TariffName,
+			&account.Price,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, &account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,206 @@
+package data
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is an asymmetric JWT key identified by kid
+// PrivateKey is nil for keys that are only kept to verify tokens issued before a rotation
+type SigningKey struct {
+	KID        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeySet holds every key accepted for verification and the kid used for signing
+type KeySet struct {
+	ActiveKID string
+	Keys      map[string]*SigningKey
+}
+
+// JWK is a public key in RFC 7517 format
+type JWK struct {
+	KTY string `json:"kty"`
+	KID string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// LoadKeySet reads keys from specs in "kid:alg:path" form, where alg is RS256 or EdDSA
+// and path points to a PEM file with either a private key or a public key
+// The active kid must refer to a private key
+func LoadKeySet(specs []string, activeKID string) (*KeySet, error) {
+	ks := &KeySet{
+		ActiveKID: activeKID,
+		Keys:      make(map[string]*SigningKey),
+	}
+
+	for _, spec := range specs {
+		parts := strings.SplitN(strings.TrimSpace(spec), ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("jwt key %q: expected kid:alg:path", spec)
+		}
+
+		kid, alg, path := parts[0], parts[1], parts[2]
+
+		if _, exists := ks.Keys[kid]; exists {
+			return nil, fmt.Errorf("jwt key %q: duplicate kid", kid)
+		}
+
+		key, err := loadSigningKey(kid, alg, path)
+		if err != nil {
+			return nil, err
+		}
+
+		ks.Keys[kid] = key
+	}
+
+	if activeKID != "" {
+		active, ok := ks.Keys[activeKID]
+		if !ok {
+			return nil, fmt.Errorf("jwt signing kid %q is not configured", activeKID)
+		}
+		if active.PrivateKey == nil {
+			return nil, fmt.Errorf("jwt signing kid %q has no private key", activeKID)
+		}
+	}
+
+	return ks, nil
+}
+
+// loadSigningKey parses a PEM file and checks that the key matches alg
+func loadSigningKey(kid, alg, path string) (*SigningKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("jwt key %q: no PEM block found in %s", kid, path)
+	}
+
+	var parsed interface{}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported PEM block %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+	}
+
+	key := &SigningKey{KID: kid}
+
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.PrivateKey = signer
+		key.PublicKey = signer.Public()
+	} else {
+		key.PublicKey = parsed
+	}
+
+	switch alg {
+	case "RS256":
+		if _, ok := key.PublicKey.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt key %q: RS256 requires an RSA key", kid)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case "EdDSA":
+		if _, ok := key.PublicKey.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt key %q: EdDSA requires an Ed25519 key", kid)
+		}
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported algorithm %q", kid, alg)
+	}
+
+	return key, nil
+}
+
+// Active returns the key used to sign new tokens, or nil if none is configured
+func (ks *KeySet) Active() *SigningKey {
+	if ks == nil || ks.ActiveKID == "" {
+		return nil
+	}
+	return ks.Keys[ks.ActiveKID]
+}
+
+// VerificationKey finds the public key for a token's kid and checks the algorithm
+func (ks *KeySet) VerificationKey(kid string, method jwt.SigningMethod) (crypto.PublicKey, error) {
+	if ks == nil {
+		return nil, errors.New("no jwt keys configured")
+	}
+
+	key, ok := ks.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+
+	if method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method for kid %q: %v", kid, method.Alg())
+	}
+
+	return key.PublicKey, nil
+}
+
+// JWKS returns the public keys in JSON Web Key format
+func (ks *KeySet) JWKS() []JWK {
+	keys := []JWK{}
+
+	if ks == nil {
+		return keys
+	}
+
+	for _, key := range ks.Keys {
+		jwk := JWK{
+			KID: key.KID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KTY = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KTY = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KID < keys[j].KID })
+
+	return keys
+}
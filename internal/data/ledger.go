@@ -0,0 +1,247 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnbalancedTransaction = errors.New("unbalanced ledger transaction")
+)
+
+// Ledger accounts used in double-entry postings
+const (
+	LedgerReceivable = "receivable" // What the subscriber account owes (per account_id)
+	LedgerRevenue    = "revenue"    // Billed services
+	LedgerCash       = "cash"       // Received payments
+)
+
+// Entry directions
+const (
+	Debit  = "debit"
+	Credit = "credit"
+)
+
+// LedgerTransaction groups entries whose debits and credits must balance
+type LedgerTransaction struct {
+	ID          int64          `json:"id"`
+	Description string         `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Entries     []*LedgerEntry `json:"entries"`
+}
+
+// LedgerEntry is one side of a double-entry posting
+type LedgerEntry struct {
+	ID            int64     `json:"id"`
+	TransactionID int64     `json:"transaction_id"`
+	Description   string    `json:"description"`
+	AccountID     *int64    `json:"account_id,omitempty"`
+	LedgerAccount string    `json:"ledger_account"`
+	Direction     string    `json:"direction"`
+	Amount        Money     `json:"amount"`
+	Currency      string    `json:"currency"`
+	InvoiceID     *int64    `json:"invoice_id,omitempty"`
+	PaymentID     *int64    `json:"payment_id,omitempty"`
+	CreatedBy     *int64    `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AccountBalance is derived from receivable entries of an account
+// A positive balance means the account has credit, a negative one means debt
+type AccountBalance struct {
+	AccountID int64  `json:"account_id"`
+	Currency  string `json:"currency"`
+	Debit     Money  `json:"debit"`
+	Credit    Money  `json:"credit"`
+	Balance   Money  `json:"balance"`
+}
+
+// Check verifies that debits equal credits in every currency
+func (t *LedgerTransaction) Check() error {
+	if len(t.Entries) < 2 {
+		return ErrUnbalancedTransaction
+	}
+
+	totals := make(map[string]Money)
+
+	for _, entry := range t.Entries {
+		if entry.Amount <= 0 {
+			return ErrUnbalancedTransaction
+		}
+
+		switch entry.Direction {
+		case Debit:
+			totals[entry.Currency] += entry.Amount
+		case Credit:
+			totals[entry.Currency] -= entry.Amount
+		default:
+			return ErrUnbalancedTransaction
+		}
+	}
+
+	for _, total := range totals {
+		if total != 0 {
+			return ErrUnbalancedTransaction
+		}
+	}
+
+	return nil
+}
+
+// postLedgerTx writes a balanced transaction within an existing database transaction
+// Used by models that must post to the ledger atomically with their own writes
+func postLedgerTx(ctx context.Context, tx *sql.Tx, transaction *LedgerTransaction) error {
+	err := transaction.Check()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO ledger_transactions (description)
+		VALUES ($1)
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, transaction.Description).Scan(&transaction.ID, &transaction.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	entryQuery := `
+		INSERT INTO ledger_entries
+			(transaction_id, account_id, ledger_account, direction, amount,
+			 currency, invoice_id, payment_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`
+
+	for _, entry := range transaction.Entries {
+		entry.TransactionID = transaction.ID
+		entry.Description = transaction.Description
+		entry.CreatedAt = transaction.CreatedAt
+
+		err = tx.QueryRowContext(ctx, entryQuery,
+			entry.TransactionID,
+			entry.AccountID,
+			entry.LedgerAccount,
+			entry.Direction,
+			entry.Amount,
+			entry.Currency,
+			entry.InvoiceID,
+			entry.PaymentID,
+			entry.CreatedBy,
+			entry.CreatedAt,
+		).Scan(&entry.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LedgerModel reads balances and entries from the ledger
+type LedgerModel struct {
+	DB *sql.DB
+}
+
+// GetBalance sums receivable entries of an account in the given currency
+func (m LedgerModel) GetBalance(accountID int64, currency string) (*AccountBalance, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE direction = 'debit'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE direction = 'credit'), 0)
+		FROM ledger_entries
+		WHERE account_id = $1 AND ledger_account = $2 AND currency = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	balance := AccountBalance{
+		AccountID: accountID,
+		Currency:  currency,
+	}
+
+	err := m.DB.QueryRowContext(ctx, query, accountID, LedgerReceivable, currency).Scan(
+		&balance.Debit,
+		&balance.Credit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	balance.Balance = balance.Credit - balance.Debit
+
+	return &balance, nil
+}
+
+// GetEntriesByAccountID lists receivable entries of an account, newest first
+func (m LedgerModel) GetEntriesByAccountID(accountID int64) ([]*LedgerEntry, error) {
+	query := `
+		SELECT
+			le.id, le.transaction_id, lt.description, le.account_id, le.ledger_account,
+			le.direction, le.amount, le.currency, le.invoice_id, le.payment_id,
+			le.created_by, le.created_at
+		FROM ledger_entries le
+		INNER JOIN ledger_transactions lt ON lt.id = le.transaction_id
+		WHERE le.account_id = $1 AND le.ledger_account = $2
+		ORDER BY le.id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, accountID, LedgerReceivable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*LedgerEntry{}
+
+	for rows.Next() {
+		var entry LedgerEntry
+		var entryAccountID, invoiceID, paymentID, createdBy sql.NullInt64
+
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TransactionID,
+			&entry.Description,
+			&entryAccountID,
+			&entry.LedgerAccount,
+			&entry.Direction,
+			&entry.Amount,
+			&entry.Currency,
+			&invoiceID,
+			&paymentID,
+			&createdBy,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if entryAccountID.Valid {
+			entry.AccountID = &entryAccountID.Int64
+		}
+
+		if invoiceID.Valid {
+			entry.InvoiceID = &invoiceID.Int64
+		}
+
+		if paymentID.Valid {
+			entry.PaymentID = &paymentID.Int64
+		}
+
+		if createdBy.Valid {
+			entry.CreatedBy = &createdBy.Int64
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
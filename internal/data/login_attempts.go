@@ -0,0 +1,199 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Login attempt counter kinds
+const (
+	AttemptsByLogin = "login"
+	AttemptsByIP    = "ip"
+)
+
+// LockoutPolicy controls progressive lockout after failed logins
+// After MaxFailures the key is locked for BaseLockout, doubling with every
+// further failure up to MaxLockout. Counters reset after FailureWindow without failures
+type LockoutPolicy struct {
+	MaxFailures   int
+	MaxIPFailures int
+	BaseLockout   time.Duration
+	MaxLockout    time.Duration
+	FailureWindow time.Duration
+}
+
+// DefaultLockoutPolicy is used unless configured otherwise
+var DefaultLockoutPolicy = LockoutPolicy{
+	MaxFailures:   5,
+	MaxIPFailures: 20,
+	BaseLockout:   time.Minute,
+	MaxLockout:    time.Hour,
+	FailureWindow: time.Hour,
+}
+
+// lockoutFor returns how long to lock a key after the given number of failures
+func (p LockoutPolicy) lockoutFor(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	lockout := p.BaseLockout
+	for i := threshold; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+
+	return lockout
+}
+
+// LoginAttemptModel tracks failed logins per login and per IP
+type LoginAttemptModel struct {
+	DB     *sql.DB
+	Policy LockoutPolicy
+}
+
+// Attempt runs verify unless the login or IP is locked and records the outcome
+// A failure counts towards both lockouts, a success clears the login counter
+// Attempts on the same login are serialized, so concurrent guesses can't all pass
+// the lock check before any of them is counted
+// Returns the verify result, or the time until which login is blocked
+func (m LoginAttemptModel) Attempt(login, ip string, verify func() (bool, error)) (bool, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	// Held until the transaction ends; the IP isn't locked so that logins from
+	// a shared address aren't queued behind each other
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1 || ':' || $2, 0))`, AttemptsByLogin, login)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	lockedUntil, err := lockedUntilTx(ctx, tx, login, ip)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	if !lockedUntil.IsZero() {
+		return false, lockedUntil, nil
+	}
+
+	ok, err := verify()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	if ok {
+		_, err = tx.ExecContext(ctx, `DELETE FROM login_attempts WHERE kind = $1 AND key = $2`, AttemptsByLogin, login)
+	} else {
+		err = m.recordFailureTx(ctx, tx, AttemptsByLogin, login, m.Policy.MaxFailures)
+		if err == nil {
+			err = m.recordFailureTx(ctx, tx, AttemptsByIP, ip, m.Policy.MaxIPFailures)
+		}
+	}
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	return ok, time.Time{}, nil
+}
+
+// lockedUntilTx returns the time until which login is blocked for this login or IP
+// The zero time means login is allowed
+func lockedUntilTx(ctx context.Context, tx *sql.Tx, login, ip string) (time.Time, error) {
+	query := `
+		SELECT MAX(locked_until)
+		FROM login_attempts
+		WHERE ((kind = $1 AND key = $2) OR (kind = $3 AND key = $4))
+		  AND locked_until > NOW()`
+
+	var lockedUntil sql.NullTime
+
+	err := tx.QueryRowContext(ctx, query, AttemptsByLogin, login, AttemptsByIP, ip).Scan(&lockedUntil)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return lockedUntil.Time, nil
+}
+
+// recordFailureTx counts a failure for one key and locks it once the threshold is reached
+// The upsert holds the row lock until the transaction ends, so the count and the lockout stay consistent
+func (m LoginAttemptModel) recordFailureTx(ctx context.Context, tx *sql.Tx, kind, key string, threshold int) error {
+	query := `
+		INSERT INTO login_attempts (kind, key, failures, last_failure_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (kind, key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $3) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING failures`
+
+	var failures int
+
+	err := tx.QueryRowContext(ctx, query, kind, key, m.Policy.FailureWindow.Seconds()).Scan(&failures)
+	if err != nil {
+		return err
+	}
+
+	lockout := m.Policy.lockoutFor(failures, threshold)
+	if lockout == 0 {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE login_attempts
+		SET locked_until = NOW() + make_interval(secs => $3)
+		WHERE kind = $1 AND key = $2`, kind, key, lockout.Seconds())
+
+	return err
+}
+
+// Reset clears the counter of a login after a successful login
+// The IP counter is kept so a valid account can't be used to unlock guessing from that IP
+func (m LoginAttemptModel) Reset(login string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM login_attempts WHERE kind = $1 AND key = $2`, AttemptsByLogin, login)
+	return err
+}
+
+// Unlock removes counters and lockouts of a login or IP
+// Returns ErrRecordNotFound if there was nothing to unlock
+func (m LoginAttemptModel) Unlock(kind, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM login_attempts WHERE kind = $1 AND key = $2`, kind, key)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutFor(t *testing.T) {
+	policy := LockoutPolicy{
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+	}
+
+	tests := []struct {
+		name      string
+		failures  int
+		threshold int
+		want      time.Duration
+	}{
+		{"no failures", 0, 5, 0},
+		{"below threshold", 4, 5, 0},
+		{"at threshold", 5, 5, time.Minute},
+		{"one over", 6, 5, 2 * time.Minute},
+		{"two over", 7, 5, 4 * time.Minute},
+		{"five over", 10, 5, 32 * time.Minute},
+		{"capped", 11, 5, time.Hour},
+		{"far over", 1000, 5, time.Hour},
+		{"ip threshold", 21, 20, 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.lockoutFor(tt.failures, tt.threshold); got != tt.want {
+				t.Errorf("lockoutFor(%d, %d) = %v, want %v", tt.failures, tt.threshold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockoutForBaseAboveMax(t *testing.T) {
+	policy := LockoutPolicy{
+		BaseLockout: 2 * time.Hour,
+		MaxLockout:  time.Hour,
+	}
+
+	if got := policy.lockoutFor(5, 5); got != time.Hour {
+		t.Errorf("lockoutFor(5, 5) = %v, want %v", got, time.Hour)
+	}
+}
@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+)
+
+// RecoveryCodeCount is how many recovery codes are issued on enrollment
+const RecoveryCodeCount = 10
+
+// MFA is the TOTP enrollment of a system account
+type MFA struct {
+	UserID       int64
+	TOTPSecret   string
+	Enabled      bool
+	LastUsedStep int64
+	CreatedAt    time.Time
+	ConfirmedAt  *time.Time
+}
+
+// MFAModel handles TOTP secrets and recovery codes
+type MFAModel struct {
+	DB *sql.DB
+}
+
+// Get fetches the enrollment of a user
+func (m MFAModel) Get(userID int64) (*MFA, error) {
+	query := `
+		SELECT user_id, totp_secret, enabled, last_used_step, created_at, confirmed_at
+		FROM system_account_mfa
+		WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var mfa MFA
+	var confirmedAt sql.NullTime
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.TOTPSecret,
+		&mfa.Enabled,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+		&confirmedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if confirmedAt.Valid {
+		mfa.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return &mfa, nil
+}
+
+// IsRequired reports whether any group of the user requires a second factor
+func (m MFAModel) IsRequired(userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM system_groups sg
+			INNER JOIN system_group_info sgi ON sgi.id = sg.group_id
+			WHERE sg.user_id = $1 AND sgi.require_mfa
+			  AND ` + activeMembership + `
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var required bool
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&required)
+	if err != nil {
+		return false, err
+	}
+
+	return required, nil
+}
+
+// SetPendingSecret stores a new unconfirmed secret, replacing a previous unconfirmed one
+// Returns ErrMFAAlreadyEnabled if the user has a confirmed secret
+func (m MFAModel) SetPendingSecret(userID int64, secret string) error {
+	query := `
+		INSERT INTO system_account_mfa (user_id, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret, created_at = NOW(), last_used_step = 0
+		WHERE NOT system_account_mfa.enabled`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// UseStep records a successfully verified time step
+// Returns false if this or a later step was already used (replayed code)
+func (m MFAModel) UseStep(userID, step int64) (bool, error) {
+	query := `
+		UPDATE system_account_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// Enable confirms the enrollment and issues a fresh set of recovery codes
+// The plaintext codes are returned once and only their hashes are stored
+func (m MFAModel) Enable(userID int64) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE system_account_mfa
+		SET enabled = TRUE, confirmed_at = NOW()
+		WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)`, userID, hashRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode consumes an unused recovery code
+// Returns false if the code is unknown or already used
+func (m MFAModel) UseRecoveryCode(userID int64, code string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM mfa_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// recoveryCodeAlphabet leaves out characters that are easy to confuse: 0/o, 1/i/l
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// generateRecoveryCode returns a code like "k7q2-m9xw-4tz8"
+func generateRecoveryCode() (string, error) {
+	return randomCode(rand.Reader, recoveryCodeAlphabet, 12)
+}
+
+// randomCode picks n characters from alphabet uniformly, adding a dash after every 4
+// Bytes past the largest multiple of the alphabet size are skipped, as taking them
+// modulo the size would favour the first characters
+func randomCode(random io.Reader, alphabet string, n int) (string, error) {
+	limit := 256 - 256%len(alphabet)
+
+	var b strings.Builder
+	buf := make([]byte, n)
+
+	for written := 0; written < n; {
+		_, err := io.ReadFull(random, buf)
+		if err != nil {
+			return "", err
+		}
+
+		for _, c := range buf {
+			if int(c) >= limit || written == n {
+				continue
+			}
+			if written > 0 && written%4 == 0 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(alphabet[int(c)%len(alphabet)])
+			written++
+		}
+	}
+
+	return b.String(), nil
+}
+
+// hashRecoveryCode normalises and hashes a recovery code
+func hashRecoveryCode(code string) []byte {
+	normalised := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	hash := sha256.Sum256([]byte(normalised))
+	return hash[:]
+}
@@ -0,0 +1,62 @@
+package data
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRandomCode(t *testing.T) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789" // 31 characters, 248 = 8*31
+
+	tests := []struct {
+		name   string
+		random []byte
+		n      int
+		want   string
+	}{
+		{"in range", []byte{0, 1, 30, 31}, 4, "ab9a"},
+		{"dashes", []byte{0, 1, 2, 3, 4, 5, 6, 7, 8}, 9, "abcd-efgh-j"},
+		{"last accepted byte", []byte{247}, 1, "9"},
+		{"biased bytes skipped", []byte{248, 255, 250, 5}, 1, "f"},
+		{"refills after skipping", []byte{255, 0, 1, 2}, 2, "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := randomCode(bytes.NewReader(tt.random), alphabet, tt.n)
+			if err != nil {
+				t.Fatalf("randomCode: unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("randomCode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomCodeShortRead(t *testing.T) {
+	_, err := randomCode(bytes.NewReader([]byte{255, 255}), "abc", 2)
+	if err == nil {
+		t.Fatal("randomCode: expected an error when the reader runs out")
+	}
+}
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatalf("generateRecoveryCode: %v", err)
+	}
+
+	groups := strings.Split(code, "-")
+	if len(groups) != 3 {
+		t.Fatalf("generateRecoveryCode = %q, want 3 groups of 4", code)
+	}
+
+	for _, group := range groups {
+		if len(group) != 4 || strings.Trim(group, recoveryCodeAlphabet) != "" {
+			t.Errorf("generateRecoveryCode = %q, group %q isn't 4 alphabet characters", code, group)
+		}
+	}
+}
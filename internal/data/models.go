@@ -3,6 +3,8 @@ package data
 import (
 	"database/sql"
 	"errors"
+
+	"biling_api/internal/password"
 )
 
 var (
@@ -15,19 +17,47 @@ type Models struct {
 	Accounts           AccountModel
 	AuthUsers          AuthUserModel
 	Groups             GroupModel
-	Permissions        PermissionModel
+	Authorizer         Authorizer
+	Functions          FunctionModel
+	Audit              AuditModel
 	Tokens             TokenModel
+	RefreshTokens      RefreshTokenModel
+	APIKeys            APIKeyModel
+	Revocations        *RevocationStore
+	LoginAttempts      LoginAttemptModel
+	MFA                MFAModel
+	PasswordResets     PasswordResetModel
 	AccountTariffLinks AccountTariffLinkModel
+	Tariffs            TariffModel
+	TariffHistory      AccountTariffHistoryModel
+	ScheduledChanges   ScheduledTariffChangeModel
+	Invoices           InvoiceModel
+	Ledger             LedgerModel
+	Payments           PaymentModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
 		Users:              UserModel{DB: db},
 		Accounts:           AccountModel{DB: db},
-		AuthUsers:          AuthUserModel{DB: db},
+		AuthUsers:          AuthUserModel{DB: db, Policy: DefaultPasswordPolicy, Hasher: password.Default},
 		Groups:             GroupModel{DB: db},
-		Permissions:        PermissionModel{DB: db},
+		Authorizer:         NewCachedAuthorizer(db, DefaultAuthorizerTTL),
+		Functions:          FunctionModel{DB: db},
+		Audit:              AuditModel{DB: db},
 		Tokens:             TokenModel{},
+		RefreshTokens:      RefreshTokenModel{DB: db},
+		APIKeys:            APIKeyModel{DB: db},
+		Revocations:        NewRevocationStore(db),
+		LoginAttempts:      LoginAttemptModel{DB: db, Policy: DefaultLockoutPolicy},
+		MFA:                MFAModel{DB: db},
+		PasswordResets:     PasswordResetModel{DB: db},
 		AccountTariffLinks: AccountTariffLinkModel{DB: db},
+		Tariffs:            TariffModel{DB: db},
+		TariffHistory:      AccountTariffHistoryModel{DB: db},
+		ScheduledChanges:   ScheduledTariffChangeModel{DB: db},
+		Invoices:           InvoiceModel{DB: db},
+		Ledger:             LedgerModel{DB: db},
+		Payments:           PaymentModel{DB: db},
 	}
 }
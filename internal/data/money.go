@@ -0,0 +1,138 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidMoneyFormat = errors.New("invalid money format")
+
+// MaxMoney is the largest amount that fits into a DECIMAL(10, 2) column
+const MaxMoney Money = 99_999_999_99
+
+// Money is an amount in minor currency units (1/100)
+// Stored as DECIMAL(10, 2) and rendered in JSON as a number with two decimals
+type Money int64
+
+// ParseMoney parses a decimal string like "100", "99.9" or "-12.50"
+// Amounts beyond MaxMoney in either direction are rejected
+func ParseMoney(s string) (Money, error) {
+	return parseMoney(s, uint64(MaxMoney/100))
+}
+
+// parseMoney parses a decimal string whose whole part is at most maxUnits
+func parseMoney(s string, maxUnits uint64) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || (hasFrac && (frac == "" || len(frac) > 2)) {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	units, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	cents, err := strconv.ParseUint(frac, 10, 8)
+	if err != nil {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	if units > maxUnits {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	m := Money(units*100 + cents)
+	if negative {
+		m = -m
+	}
+
+	return m, nil
+}
+
+// String formats the amount with exactly two decimals
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, int64(m)/100, int64(m)%100)
+}
+
+// MarshalJSON renders the amount as a JSON number, e.g. 100.00
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts both a JSON number and a quoted decimal string
+func (m *Money) UnmarshalJSON(jsonValue []byte) error {
+	value := string(jsonValue)
+
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	parsed, err := ParseMoney(value)
+	if err != nil {
+		return ErrInvalidMoneyFormat
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns
+// Ledger sums can exceed a single DECIMAL(10, 2) value, so only int64 limits the amount
+func (m *Money) Scan(src interface{}) error {
+	var (
+		parsed Money
+		err    error
+	)
+
+	switch v := src.(type) {
+	case []byte:
+		parsed, err = parseMoney(string(v), math.MaxInt64/100-1)
+	case string:
+		parsed, err = parseMoney(v, math.MaxInt64/100-1)
+	case int64:
+		parsed = Money(v * 100)
+	case nil:
+		parsed = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, passing the amount as a decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{"whole", "100", 100_00, false},
+		{"one decimal", "99.9", 99_90, false},
+		{"two decimals", "12.05", 12_05, false},
+		{"negative", "-12.50", -12_50, false},
+		{"explicit plus", "+3.1", 3_10, false},
+		{"surrounding spaces", " 7.00 ", 7_00, false},
+		{"zero", "0", 0, false},
+		{"leading zeros", "007.01", 7_01, false},
+		{"empty", "", 0, true},
+		{"sign only", "-", 0, true},
+		{"no whole part", ".50", 0, true},
+		{"trailing dot", "5.", 0, true},
+		{"three decimals", "1.005", 0, true},
+		{"two dots", "1.2.3", 0, true},
+		{"letters", "12a", 0, true},
+		{"exponent", "1e5", 0, true},
+		{"comma", "1,50", 0, true},
+		{"max", "99999999.99", MaxMoney, false},
+		{"min", "-99999999.99", -MaxMoney, false},
+		{"above max", "100000000.00", 0, true},
+		{"below min", "-100000000", 0, true},
+		{"overflow", "92233720368547758.07", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMoneyFormat) {
+					t.Fatalf("ParseMoney(%q) error = %v, want ErrInvalidMoneyFormat", tt.input, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseMoney(%q) unexpected error: %v", tt.input, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1_50, "1.50"},
+		{100_00, "100.00"},
+		{-5, "-0.05"},
+		{-12_50, "-12.50"},
+		{MaxMoney, "99999999.99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.money.String(); got != tt.want {
+				t.Errorf("Money(%d).String() = %q, want %q", tt.money, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{"number", `12.5`, 12_50, false},
+		{"string", `"12.50"`, 12_50, false},
+		{"integer", `3`, 3_00, false},
+		{"too precise", `1.001`, 0, true},
+		{"not a number", `"abc"`, 0, true},
+		{"null", `null`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.input), &got)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unmarshal %s: expected an error, got %d", tt.input, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unmarshal %s: unexpected error: %v", tt.input, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("unmarshal %s = %d, want %d", tt.input, got, tt.want)
+			}
+
+			out, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("marshal %d: %v", got, err)
+			}
+
+			if string(out) != got.String() {
+				t.Errorf("marshal %d = %s, want %s", got, out, got.String())
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Money
+		wantErr bool
+	}{
+		{"bytes", []byte("150.25"), 150_25, false},
+		{"string", "0.10", 10, false},
+		{"int64", int64(42), 42_00, false},
+		{"nil", nil, 0, false},
+		{"sum above MaxMoney", []byte("1234567890.12"), 1_234_567_890_12, false},
+		{"overflow", []byte("92233720368547758.07"), 0, true},
+		{"bad bytes", []byte("x"), 0, true},
+		{"float", 1.5, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Money(-1)
+			err := got.Scan(tt.src)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v): expected an error", tt.src)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Scan(%v): unexpected error: %v", tt.src, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"biling_api/internal/validator"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
+
+// PasswordPolicy describes which new passwords are accepted
+type PasswordPolicy struct {
+	MinLength int
+	DenyList  map[string]bool // Lowercased passwords that are never accepted
+}
+
+// DefaultPasswordPolicy is used until main applies the configured one
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+}
+
+// Validate checks a new password against the policy
+func (p PasswordPolicy) Validate(v *validator.Validator, login, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= p.MinLength, "password", "is too short")
+	// bcrypt ignores everything after 72 bytes
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.Check(!strings.EqualFold(password, login), "password", "must not be the same as the login")
+	v.Check(!p.DenyList[strings.ToLower(password)], "password", "is too common")
+}
+
+// LoadPasswordDenyList reads one password per line; empty lines and # comments are skipped
+func LoadPasswordDenyList(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	denyList := make(map[string]bool)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denyList[strings.ToLower(line)] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return denyList, nil
+}
+
+// PasswordResetToken is a single-use token issued by an admin
+// Only the SHA-256 hash is stored in the database
+type PasswordResetToken struct {
+	Plaintext string    `json:"reset_token"`
+	Hash      []byte    `json:"-"`
+	UserID    int64     `json:"system_account_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasswordResetModel handles admin-issued password reset tokens
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+// New issues a reset token and invalidates earlier unused tokens of the user
+func (m PasswordResetModel) New(userID int64, ttl time.Duration, createdBy int64) (*PasswordResetToken, error) {
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &PasswordResetToken{
+		Plaintext: base64.RawURLEncoding.EncodeToString(randomBytes),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM password_reset_tokens
+		WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at, created_by)
+		VALUES ($1, $2, $3, $4)`,
+		token.Hash, token.UserID, token.ExpiresAt, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// GetUserID returns the owner of an unused, unexpired reset token
+func (m PasswordResetModel) GetUserID(plaintext string) (int64, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `
+		SELECT user_id
+		FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var userID int64
+
+	err := m.DB.QueryRowContext(ctx, query, hash[:]).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrInvalidResetToken
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+// Consume marks the token as used; a token can be consumed only once
+func (m PasswordResetModel) Consume(plaintext string) error {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, hash[:])
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidResetToken
+	}
+
+	return nil
+}
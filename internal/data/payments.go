@@ -0,0 +1,289 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"biling_api/internal/validator"
+)
+
+var (
+	ErrPaymentRefMismatch = errors.New("payment reference mismatch")
+)
+
+// PaymentMethods lists accepted payment methods
+var PaymentMethods = []string{"cash", "card", "bank_transfer", "online"}
+
+// Payment is an incoming payment recorded by an operator
+type Payment struct {
+	ID          int64                `json:"id"`
+	AccountID   int64                `json:"account_id"`
+	Method      string               `json:"method"`
+	ExternalRef string               `json:"external_ref"`
+	Amount      Money                `json:"amount"`
+	Currency    string               `json:"currency"`
+	CreatedBy   *int64               `json:"created_by,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Allocations []*PaymentAllocation `json:"allocations"`
+	Unallocated Money                `json:"unallocated"`
+}
+
+// PaymentAllocation is the part of a payment applied to one invoice
+type PaymentAllocation struct {
+	ID        int64 `json:"id"`
+	PaymentID int64 `json:"payment_id"`
+	InvoiceID int64 `json:"invoice_id"`
+	Amount    Money `json:"amount"`
+}
+
+// ValidatePayment checks payment fields before they are written
+func ValidatePayment(v *validator.Validator, payment *Payment) {
+	v.Check(payment.Method != "", "method", "must be provided")
+	v.Check(validator.In(payment.Method, PaymentMethods...), "method", "must be one of cash, card, bank_transfer, online")
+
+	v.Check(payment.ExternalRef != "", "external_ref", "must be provided")
+	v.Check(len(payment.ExternalRef) <= 255, "external_ref", "must not be more than 255 bytes long")
+
+	v.Check(payment.Amount > 0, "amount", "must be greater than zero")
+	v.Check(payment.Amount <= MaxMoney, "amount", "is too large")
+}
+
+// PaymentModel handles database operations for payments
+type PaymentModel struct {
+	DB *sql.DB
+}
+
+// Insert records a payment, allocates it to the oldest open invoices of the account
+// and writes its ledger posting, all in one transaction
+// If a payment with the same external_ref exists, it is returned unchanged with created = false
+// Returns ErrPaymentRefMismatch if that payment belongs to another account or has another amount
+func (m PaymentModel) Insert(payment *Payment, posting *LedgerTransaction) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO payments (account_id, method, external_ref, amount, currency, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (external_ref) DO NOTHING
+		RETURNING id, created_at`
+
+	args := []interface{}{
+		payment.AccountID,
+		payment.Method,
+		payment.ExternalRef,
+		payment.Amount,
+		payment.Currency,
+		payment.CreatedBy,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&payment.ID, &payment.CreatedAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+
+		// Already recorded - replay the original result
+		existing, err := m.GetByExternalRef(payment.ExternalRef)
+		if err != nil {
+			return false, err
+		}
+
+		if existing.AccountID != payment.AccountID || existing.Amount != payment.Amount {
+			return false, ErrPaymentRefMismatch
+		}
+
+		*payment = *existing
+		return false, nil
+	}
+
+	err = allocatePaymentTx(ctx, tx, payment)
+	if err != nil {
+		return false, err
+	}
+
+	if posting != nil {
+		for _, entry := range posting.Entries {
+			entry.PaymentID = &payment.ID
+		}
+
+		err = postLedgerTx(ctx, tx, posting)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// allocatePaymentTx spreads the payment over open invoices, oldest period first
+// Whatever is left stays on the account as credit and goes to the next issued invoice
+func allocatePaymentTx(ctx context.Context, tx *sql.Tx, payment *Payment) error {
+	invoicesQuery := `
+		SELECT i.id, i.total - COALESCE((
+			SELECT SUM(pa.amount) FROM payment_allocations pa WHERE pa.invoice_id = i.id
+		), 0)
+		FROM invoices i
+		WHERE i.account_id = $1 AND i.status = 'open' AND i.currency = $2
+		ORDER BY i.period_start, i.id
+		FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, invoicesQuery, payment.AccountID, payment.Currency)
+	if err != nil {
+		return err
+	}
+
+	type openInvoice struct {
+		id          int64
+		outstanding Money
+	}
+
+	var open []openInvoice
+
+	for rows.Next() {
+		var inv openInvoice
+		if err := rows.Scan(&inv.id, &inv.outstanding); err != nil {
+			rows.Close()
+			return err
+		}
+		open = append(open, inv)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	allocationQuery := `
+		INSERT INTO payment_allocations (payment_id, invoice_id, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id`
+
+	remaining := payment.Amount
+	payment.Allocations = []*PaymentAllocation{}
+
+	for _, inv := range open {
+		if remaining <= 0 {
+			break
+		}
+
+		amount := inv.outstanding
+		if amount > remaining {
+			amount = remaining
+		}
+
+		if amount > 0 {
+			allocation := &PaymentAllocation{
+				PaymentID: payment.ID,
+				InvoiceID: inv.id,
+				Amount:    amount,
+			}
+
+			err = tx.QueryRowContext(ctx, allocationQuery, allocation.PaymentID, allocation.InvoiceID, allocation.Amount).Scan(&allocation.ID)
+			if err != nil {
+				return err
+			}
+
+			payment.Allocations = append(payment.Allocations, allocation)
+			remaining -= amount
+		}
+
+		if amount == inv.outstanding {
+			_, err = tx.ExecContext(ctx, `UPDATE invoices SET status = 'paid' WHERE id = $1`, inv.id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	payment.Unallocated = remaining
+
+	return nil
+}
+
+// GetByExternalRef fetches a payment with its allocations by external reference
+func (m PaymentModel) GetByExternalRef(externalRef string) (*Payment, error) {
+	query := `
+		SELECT id, account_id, method, external_ref, amount, currency, created_by, created_at
+		FROM payments
+		WHERE external_ref = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var payment Payment
+	var createdBy sql.NullInt64
+
+	err := m.DB.QueryRowContext(ctx, query, externalRef).Scan(
+		&payment.ID,
+		&payment.AccountID,
+		&payment.Method,
+		&payment.ExternalRef,
+		&payment.Amount,
+		&payment.Currency,
+		&createdBy,
+		&payment.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if createdBy.Valid {
+		payment.CreatedBy = &createdBy.Int64
+	}
+
+	allocationsQuery := `
+		SELECT id, payment_id, invoice_id, amount
+		FROM payment_allocations
+		WHERE payment_id = $1
+		ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, allocationsQuery, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payment.Allocations = []*PaymentAllocation{}
+	payment.Unallocated = payment.Amount
+
+	for rows.Next() {
+		var allocation PaymentAllocation
+
+		err := rows.Scan(
+			&allocation.ID,
+			&allocation.PaymentID,
+			&allocation.InvoiceID,
+			&allocation.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		payment.Allocations = append(payment.Allocations, &allocation)
+		payment.Unallocated -= allocation.Amount
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
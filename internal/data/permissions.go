@@ -1,72 +1,54 @@
 package data
 
-import (
-	"context"
-	"database/sql"
-	"time"
-)
-
 // FID константы — идентификаторы функций API
-// Соответствуют значениям fid в таблице system_rights
+// Соответствуют значениям fid в таблицах system_functions и system_rights;
+// описание каждого FID - в FunctionRegistry (functions.go)
 const (
 	FIDAccountsRead  int64 = 1 // Чтение аккаунтов
 	FIDTariffsRead   int64 = 2 // Чтение тарифов
 	FIDTariffsUpdate int64 = 3 // Обновление тарифов
-)
 
-// PermissionModel обрабатывает операции с правами
-type PermissionModel struct {
-	DB *sql.DB
-}
+	FIDTariffCatalogCreate int64 = 4 // Создание тарифов в каталоге
+	FIDTariffCatalogUpdate int64 = 5 // Редактирование тарифов в каталоге
+	FIDTariffCatalogDelete int64 = 6 // Удаление тарифов из каталога
 
-// HasPermission проверяет, есть ли у пользователя право (fid) через его группы
-func (m PermissionModel) HasPermission(userID, fid int64) (bool, error) {
-	query := `
-		SELECT EXISTS (
-			SELECT 1 
-			FROM system_groups sg
-			INNER JOIN system_rights sr ON sg.group_id = sr.group_id
-			WHERE sg.user_id = $1 AND sr.fid = $2
-		)`
+	FIDInvoicesRead int64 = 7 // Просмотр счетов на оплату
+	FIDLedgerRead   int64 = 8 // Просмотр баланса и проводок
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	FIDPaymentsCreate int64 = 9 // Внесение платежей
 
-	var hasPermission bool
-	err := m.DB.QueryRowContext(ctx, query, userID, fid).Scan(&hasPermission)
-	if err != nil {
-		return false, err
-	}
+	FIDTokensRevoke int64 = 10 // Отзыв токенов
+	FIDLoginUnlock  int64 = 11 // Снятие блокировки входа
 
-	return hasPermission, nil
-}
+	FIDSystemAccountsRead   int64 = 12 // Просмотр системных пользователей
+	FIDSystemAccountsCreate int64 = 13 // Создание системных пользователей
+	FIDSystemAccountsUpdate int64 = 14 // Редактирование системных пользователей
+	FIDSystemAccountsDelete int64 = 15 // Удаление и восстановление системных пользователей
 
-// GetUserPermissions возвращает все FID пользователя
-func (m PermissionModel) GetUserPermissions(userID int64) ([]int64, error) {
-	query := `
-		SELECT DISTINCT sr.fid
-		FROM system_groups sg
-		INNER JOIN system_rights sr ON sg.group_id = sr.group_id
-		WHERE sg.user_id = $1
-		ORDER BY sr.fid`
+	FIDPasswordReset int64 = 16 // Выдача токена сброса пароля
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	FIDGroupsRead         int64 = 17 // Просмотр групп
+	FIDGroupsCreate       int64 = 18 // Создание групп
+	FIDGroupsUpdate       int64 = 19 // Переименование групп
+	FIDGroupsDelete       int64 = 20 // Удаление групп
+	FIDGroupRightsGrant   int64 = 21 // Выдача прав группе
+	FIDGroupRightsRevoke  int64 = 22 // Отзыв прав у группы
+	FIDGroupMembersAdd    int64 = 23 // Добавление участников в группу
+	FIDGroupMembersRemove int64 = 24 // Удаление участников из группы
 
-	rows, err := m.DB.QueryContext(ctx, query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	FIDPermissionsExplain int64 = 25 // Разбор проверки прав
 
-	var permissions []int64
-	for rows.Next() {
-		var fid int64
-		if err := rows.Scan(&fid); err != nil {
-			return nil, err
-		}
-		permissions = append(permissions, fid)
-	}
+	FIDAPIKeysRead   int64 = 26 // Просмотр API-ключей
+	FIDAPIKeysCreate int64 = 27 // Выпуск API-ключей
+	FIDAPIKeysRevoke int64 = 28 // Отзыв API-ключей
 
-	return permissions, rows.Err()
-}
+	FIDImpersonate int64 = 29 // Вход от имени системного пользователя
+
+	FIDUsersRead   int64 = 30 // Просмотр пользователей
+	FIDUsersCreate int64 = 31 // Создание пользователей
+	FIDUsersUpdate int64 = 32 // Редактирование пользователей
+	FIDUsersDelete int64 = 33 // Удаление пользователей
+
+	FIDAccountsCreate int64 = 34 // Создание аккаунтов
+	FIDAccountsUpdate int64 = 35 // Редактирование аккаунтов
+)
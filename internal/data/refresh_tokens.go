@@ -0,0 +1,237 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRefreshTokenReuse = errors.New("refresh token reuse")
+)
+
+// RefreshToken is an opaque long-lived token exchanged for new access tokens
+// Only the SHA-256 hash is stored in the database
+type RefreshToken struct {
+	Plaintext string    `json:"refresh_token"`
+	Hash      []byte    `json:"-"`
+	FamilyID  string    `json:"-"`
+	UserID    int64     `json:"-"`
+	ExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// RefreshTokenModel handles rotating refresh tokens
+type RefreshTokenModel struct {
+	DB *sql.DB
+}
+
+// generateRefreshToken creates a random token in the given family
+func generateRefreshToken(userID int64, familyID string, ttl time.Duration) (*RefreshToken, error) {
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &RefreshToken{
+		Plaintext: base64.RawURLEncoding.EncodeToString(randomBytes),
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	return token, nil
+}
+
+// newFamilyID returns a random identifier for a new login session
+func newFamilyID() (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// hashRefreshToken returns the lookup hash of a plaintext token
+func hashRefreshToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+// insertRefreshToken stores a token using either the DB or a transaction
+func insertRefreshToken(ctx context.Context, db interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}, token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (token_hash, family_id, user_id, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err := db.ExecContext(ctx, query, token.Hash, token.FamilyID, token.UserID, token.ExpiresAt)
+	return err
+}
+
+// New issues the first refresh token of a new login session
+func (m RefreshTokenModel) New(userID int64, ttl time.Duration) (*RefreshToken, error) {
+	familyID, err := newFamilyID()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateRefreshToken(userID, familyID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = insertRefreshToken(ctx, m.DB, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Rotate exchanges a valid refresh token for a new one in the same family
+// Presenting an already rotated token revokes the whole family and returns ErrRefreshTokenReuse
+// Unknown, expired or revoked tokens return ErrInvalidToken
+func (m RefreshTokenModel) Rotate(plaintext string, ttl time.Duration) (*RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, family_id, user_id, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`
+
+	var id, userID int64
+	var familyID string
+	var expiresAt time.Time
+	var usedAt, revokedAt sql.NullTime
+
+	err = tx.QueryRowContext(ctx, query, hashRefreshToken(plaintext)).Scan(
+		&id,
+		&familyID,
+		&userID,
+		&expiresAt,
+		&usedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrInvalidToken
+		default:
+			return nil, err
+		}
+	}
+
+	if revokedAt.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if usedAt.Valid {
+		// The token was already exchanged: someone holds a stolen copy
+		_, err = tx.ExecContext(ctx, `
+			UPDATE refresh_tokens
+			SET revoked_at = NOW()
+			WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReuse
+	}
+
+	if time.Now().After(expiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateRefreshToken(userID, familyID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertRefreshToken(ctx, tx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RevokeFamily revokes every token of the session the given token belongs to
+// Returns ErrInvalidToken if the token is unknown
+func (m RefreshTokenModel) RevokeFamily(plaintext string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+		  AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE token_hash = $1)`,
+		hashRefreshToken(plaintext)).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrInvalidToken
+	}
+
+	_, err = m.DB.ExecContext(ctx, query, hashRefreshToken(plaintext))
+	return err
+}
+
+// RevokeAllForUser revokes every refresh token of a system account
+func (m RefreshTokenModel) RevokeAllForUser(userID int64) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
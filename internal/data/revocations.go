@@ -0,0 +1,199 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// RevocationStore keeps revoked access tokens in Postgres and mirrors them in memory
+// The middleware only reads the in-memory copy; Load refreshes it so that
+// revocations made by other instances are picked up
+type RevocationStore struct {
+	DB *sql.DB
+
+	mu     sync.RWMutex
+	tokens map[string]time.Time // jti -> token expiry
+	users  map[int64]time.Time  // user_id -> tokens issued before this are revoked
+}
+
+// NewRevocationStore creates an empty store; call Load to fill it
+func NewRevocationStore(db *sql.DB) *RevocationStore {
+	return &RevocationStore{
+		DB:     db,
+		tokens: make(map[string]time.Time),
+		users:  make(map[int64]time.Time),
+	}
+}
+
+// IsRevoked reports whether the token was revoked individually or together with all tokens of its user
+func (s *RevocationStore) IsRevoked(claims *Claims) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[claims.ID]; ok {
+		return true
+	}
+
+	if revokedBefore, ok := s.users[claims.AuthUserID]; ok {
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(revokedBefore) {
+			return true
+		}
+	}
+
+	// Ending the actor's sessions also ends their impersonation sessions
+	if claims.Actor != nil {
+		if revokedBefore, ok := s.users[claims.Actor.AuthUserID]; ok {
+			if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(revokedBefore) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Load replaces the in-memory copy with the current database state
+func (s *RevocationStore) Load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tokens := make(map[string]time.Time)
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > NOW()`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return err
+		}
+		tokens[jti] = expiresAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	users := make(map[int64]time.Time)
+
+	userRows, err := s.DB.QueryContext(ctx, `SELECT user_id, revoked_before FROM revoked_token_users`)
+	if err != nil {
+		return err
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		var userID int64
+		var revokedBefore time.Time
+
+		if err := userRows.Scan(&userID, &revokedBefore); err != nil {
+			return err
+		}
+		users[userID] = revokedBefore
+	}
+
+	if err = userRows.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens = tokens
+	s.users = users
+	s.mu.Unlock()
+
+	return nil
+}
+
+// RevokeToken revokes a single access token by its jti
+func (s *RevocationStore) RevokeToken(jti string, userID *int64, expiresAt time.Time, revokedBy *int64) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (jti) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, jti, userID, expiresAt, revokedBy)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = expiresAt
+	s.mu.Unlock()
+
+	return nil
+}
+
+// ConsumeToken revokes a single-use token and reports whether this call was the one to do it
+// The database decides, so two instances can't both accept the same token
+func (s *RevocationStore) ConsumeToken(jti string, userID *int64, expiresAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, jti, userID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = expiresAt
+	s.mu.Unlock()
+
+	return rowsAffected == 1, nil
+}
+
+// RevokeAllForUser revokes every access token issued to a user up to now
+func (s *RevocationStore) RevokeAllForUser(userID int64, revokedBy *int64) error {
+	// iat has one-second precision, so round up to cover tokens issued in the current second
+	revokedBefore := time.Now().Truncate(time.Second).Add(time.Second)
+
+	query := `
+		INSERT INTO revoked_token_users (user_id, revoked_before, revoked_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET revoked_before = EXCLUDED.revoked_before,
+			revoked_by = EXCLUDED.revoked_by,
+			revoked_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, userID, revokedBefore, revokedBy)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = revokedBefore
+	s.mu.Unlock()
+
+	return nil
+}
+
+// DeleteExpired removes revocations of tokens that have expired anyway
+func (s *RevocationStore) DeleteExpired() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= NOW()`)
+	return err
+}
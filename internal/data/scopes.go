@@ -0,0 +1,183 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"biling_api/internal/validator"
+)
+
+// Scope kinds that can restrict a grant to part of the accounts
+const (
+	ScopeAccountRange = "account_range"
+	ScopeRegion       = "region"
+	ScopeTag          = "tag"
+)
+
+// Scope restricts a grant (group_id, fid) to matching accounts
+// A grant without scopes covers every account
+type Scope struct {
+	Kind  string `json:"kind"`
+	From  *int64 `json:"from,omitempty"`  // account_range: first account ID
+	To    *int64 `json:"to,omitempty"`    // account_range: last account ID
+	Value string `json:"value,omitempty"` // region or tag
+}
+
+// ValidateScopes checks scopes attached to a grant
+func ValidateScopes(v *validator.Validator, scopes []Scope) {
+	v.Check(len(scopes) <= 100, "scopes", "must not contain more than 100 entries")
+
+	for _, s := range scopes {
+		switch s.Kind {
+		case ScopeAccountRange:
+			v.Check(s.From != nil && s.To != nil, "scopes", "account_range needs from and to")
+			v.Check(s.From == nil || s.To == nil || (*s.From > 0 && *s.From <= *s.To), "scopes", "account_range needs 0 < from <= to")
+			v.Check(s.Value == "", "scopes", "account_range does not take a value")
+		case ScopeRegion, ScopeTag:
+			v.Check(s.Value != "", "scopes", s.Kind+" needs a value")
+			v.Check(len(s.Value) <= 100, "scopes", "value must not be more than 100 bytes long")
+			v.Check(s.From == nil && s.To == nil, "scopes", s.Kind+" does not take from and to")
+		default:
+			v.AddError("scopes", "kind must be account_range, region or tag")
+		}
+	}
+}
+
+// Matches reports whether the account falls into the scope
+func (s Scope) Matches(account *Account) bool {
+	switch s.Kind {
+	case ScopeAccountRange:
+		return s.From != nil && s.To != nil && account.ID >= *s.From && account.ID <= *s.To
+	case ScopeRegion:
+		return account.Region != nil && *account.Region == s.Value
+	case ScopeTag:
+		return slices.Contains(account.Tags, s.Value)
+	default:
+		return false
+	}
+}
+
+// Equal reports whether both scopes select the same accounts the same way
+func (s Scope) Equal(other Scope) bool {
+	return s.Kind == other.Kind &&
+		s.Value == other.Value &&
+		equalInt64Ptr(s.From, other.From) &&
+		equalInt64Ptr(s.To, other.To)
+}
+
+func equalInt64Ptr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// AccessScope is what a user may reach with one FID: the union of all their grants of it
+type AccessScope struct {
+	Unrestricted bool    // At least one grant has no scopes
+	Scopes       []Scope // Used only when not unrestricted
+}
+
+// Allows reports whether the account is covered
+func (a *AccessScope) Allows(account *Account) bool {
+	if a == nil {
+		return false
+	}
+
+	if a.Unrestricted {
+		return true
+	}
+
+	for _, s := range a.Scopes {
+		if s.Matches(account) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter keeps only covered accounts
+func (a *AccessScope) Filter(accounts []*Account) []*Account {
+	if a != nil && a.Unrestricted {
+		return accounts
+	}
+
+	allowed := []*Account{}
+
+	for _, account := range accounts {
+		if a.Allows(account) {
+			allowed = append(allowed, account)
+		}
+	}
+
+	return allowed
+}
+
+// Covers reports whether a reaches at least every account other reaches
+// Restricted scopes are compared entry by entry, so an equivalent but differently written scope doesn't count
+func (a *AccessScope) Covers(other *AccessScope) bool {
+	if other == nil {
+		return true
+	}
+
+	if a == nil {
+		return false
+	}
+
+	if a.Unrestricted {
+		return true
+	}
+
+	if other.Unrestricted {
+		return false
+	}
+
+	for _, s := range other.Scopes {
+		if !slices.ContainsFunc(a.Scopes, s.Equal) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// accountCondition returns an SQL condition on an accounts row aliased a
+// that holds for covered accounts; its parameters are appended to args
+func (a *AccessScope) accountCondition(args *[]interface{}) string {
+	if a == nil {
+		return "FALSE"
+	}
+
+	if a.Unrestricted {
+		return "TRUE"
+	}
+
+	param := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	conditions := []string{}
+
+	for _, s := range a.Scopes {
+		switch s.Kind {
+		case ScopeAccountRange:
+			if s.From != nil && s.To != nil {
+				conditions = append(conditions, fmt.Sprintf("a.id BETWEEN %s AND %s", param(*s.From), param(*s.To)))
+			}
+		case ScopeRegion:
+			conditions = append(conditions, "a.region = "+param(s.Value))
+		case ScopeTag:
+			conditions = append(conditions, param(s.Value)+" = ANY(a.tags)")
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "FALSE"
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
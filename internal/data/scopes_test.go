@@ -0,0 +1,229 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"biling_api/internal/validator"
+)
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func stringPtr(v string) *string {
+	return &v
+}
+
+func accountRange(from, to int64) Scope {
+	return Scope{Kind: ScopeAccountRange, From: int64Ptr(from), To: int64Ptr(to)}
+}
+
+func TestScopeMatches(t *testing.T) {
+	account := &Account{ID: 42, Region: stringPtr("dushanbe"), Tags: []string{"vip", "b2b"}}
+	noRegion := &Account{ID: 42}
+
+	tests := []struct {
+		name    string
+		scope   Scope
+		account *Account
+		want    bool
+	}{
+		{"range inside", accountRange(40, 50), account, true},
+		{"range lower bound", accountRange(42, 50), account, true},
+		{"range upper bound", accountRange(1, 42), account, true},
+		{"range below", accountRange(43, 50), account, false},
+		{"range above", accountRange(1, 41), account, false},
+		{"range without to", Scope{Kind: ScopeAccountRange, From: int64Ptr(1)}, account, false},
+		{"region", Scope{Kind: ScopeRegion, Value: "dushanbe"}, account, true},
+		{"other region", Scope{Kind: ScopeRegion, Value: "khujand"}, account, false},
+		{"region of account without one", Scope{Kind: ScopeRegion, Value: "dushanbe"}, noRegion, false},
+		{"tag", Scope{Kind: ScopeTag, Value: "b2b"}, account, true},
+		{"missing tag", Scope{Kind: ScopeTag, Value: "retail"}, account, false},
+		{"tag of account without tags", Scope{Kind: ScopeTag, Value: "vip"}, noRegion, false},
+		{"unknown kind", Scope{Kind: "owner", Value: "vip"}, account, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Matches(tt.account); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessScopeAllows(t *testing.T) {
+	account := &Account{ID: 7, Region: stringPtr("dushanbe")}
+
+	tests := []struct {
+		name  string
+		scope *AccessScope
+		want  bool
+	}{
+		{"nil", nil, false},
+		{"no grants", &AccessScope{}, false},
+		{"unrestricted", &AccessScope{Unrestricted: true}, true},
+		{"unrestricted ignores scopes", &AccessScope{Unrestricted: true, Scopes: []Scope{accountRange(100, 200)}}, true},
+		{"one matching scope", &AccessScope{Scopes: []Scope{accountRange(100, 200), {Kind: ScopeRegion, Value: "dushanbe"}}}, true},
+		{"no matching scope", &AccessScope{Scopes: []Scope{accountRange(100, 200)}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Allows(account); got != tt.want {
+				t.Errorf("Allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessScopeFilter(t *testing.T) {
+	accounts := []*Account{
+		{ID: 1, Tags: []string{"vip"}},
+		{ID: 5},
+		{ID: 10, Tags: []string{"vip"}},
+	}
+
+	tests := []struct {
+		name  string
+		scope *AccessScope
+		want  []int64
+	}{
+		{"nil", nil, []int64{}},
+		{"no grants", &AccessScope{}, []int64{}},
+		{"unrestricted", &AccessScope{Unrestricted: true}, []int64{1, 5, 10}},
+		{"range", &AccessScope{Scopes: []Scope{accountRange(2, 10)}}, []int64{5, 10}},
+		{"tag", &AccessScope{Scopes: []Scope{{Kind: ScopeTag, Value: "vip"}}}, []int64{1, 10}},
+		{"union", &AccessScope{Scopes: []Scope{accountRange(5, 5), {Kind: ScopeTag, Value: "vip"}}}, []int64{1, 5, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.scope.Filter(accounts)
+
+			if got == nil {
+				t.Fatal("Filter returned nil, want an empty slice")
+			}
+
+			ids := []int64{}
+			for _, a := range got {
+				ids = append(ids, a.ID)
+			}
+
+			if len(ids) != len(tt.want) {
+				t.Fatalf("Filter = %v, want %v", ids, tt.want)
+			}
+
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("Filter = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessScopeCovers(t *testing.T) {
+	region := Scope{Kind: ScopeRegion, Value: "dushanbe"}
+	tag := Scope{Kind: ScopeTag, Value: "vip"}
+
+	tests := []struct {
+		name  string
+		a     *AccessScope
+		other *AccessScope
+		want  bool
+	}{
+		{"other nil", nil, nil, true},
+		{"a nil", nil, &AccessScope{Scopes: []Scope{region}}, false},
+		{"unrestricted covers unrestricted", &AccessScope{Unrestricted: true}, &AccessScope{Unrestricted: true}, true},
+		{"unrestricted covers scoped", &AccessScope{Unrestricted: true}, &AccessScope{Scopes: []Scope{region}}, true},
+		{"scoped doesn't cover unrestricted", &AccessScope{Scopes: []Scope{region, tag}}, &AccessScope{Unrestricted: true}, false},
+		{"same scopes", &AccessScope{Scopes: []Scope{region, tag}}, &AccessScope{Scopes: []Scope{tag, region}}, true},
+		{"subset", &AccessScope{Scopes: []Scope{region, tag}}, &AccessScope{Scopes: []Scope{tag}}, true},
+		{"superset", &AccessScope{Scopes: []Scope{tag}}, &AccessScope{Scopes: []Scope{region, tag}}, false},
+		{"same range", &AccessScope{Scopes: []Scope{accountRange(1, 100)}}, &AccessScope{Scopes: []Scope{accountRange(1, 100)}}, true},
+		// Ranges are compared as written, not by the accounts they contain
+		{"narrower range", &AccessScope{Scopes: []Scope{accountRange(1, 100)}}, &AccessScope{Scopes: []Scope{accountRange(10, 20)}}, false},
+		{"other value", &AccessScope{Scopes: []Scope{region}}, &AccessScope{Scopes: []Scope{{Kind: ScopeRegion, Value: "khujand"}}}, false},
+		{"other empty", &AccessScope{Scopes: []Scope{region}}, &AccessScope{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Covers(tt.other); got != tt.want {
+				t.Errorf("Covers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessScopeAccountCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    *AccessScope
+		want     string
+		wantArgs int // Including the caller's argument
+	}{
+		{"nil", nil, "FALSE", 1},
+		{"unrestricted", &AccessScope{Unrestricted: true}, "TRUE", 1},
+		{"no grants", &AccessScope{}, "FALSE", 1},
+		{"range", &AccessScope{Scopes: []Scope{accountRange(1, 9)}}, "(a.id BETWEEN $2 AND $3)", 3},
+		{
+			"region or tag",
+			&AccessScope{Scopes: []Scope{{Kind: ScopeRegion, Value: "dushanbe"}, {Kind: ScopeTag, Value: "vip"}}},
+			"(a.region = $2 OR $3 = ANY(a.tags))",
+			3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One argument is already taken by the caller's query
+			args := []interface{}{"taken"}
+
+			got := tt.scope.accountCondition(&args)
+
+			if got != tt.want {
+				t.Errorf("accountCondition = %q, want %q", got, tt.want)
+			}
+
+			if len(args) != tt.wantArgs {
+				t.Errorf("args = %v, want %d entries", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []Scope
+		valid  bool
+	}{
+		{"none", nil, true},
+		{"range", []Scope{accountRange(1, 10)}, true},
+		{"single account range", []Scope{accountRange(5, 5)}, true},
+		{"region and tag", []Scope{{Kind: ScopeRegion, Value: "dushanbe"}, {Kind: ScopeTag, Value: "vip"}}, true},
+		{"range without to", []Scope{{Kind: ScopeAccountRange, From: int64Ptr(1)}}, false},
+		{"reversed range", []Scope{accountRange(10, 1)}, false},
+		{"range from zero", []Scope{accountRange(0, 10)}, false},
+		{"range with value", []Scope{{Kind: ScopeAccountRange, From: int64Ptr(1), To: int64Ptr(2), Value: "x"}}, false},
+		{"region without value", []Scope{{Kind: ScopeRegion}}, false},
+		{"tag with range", []Scope{{Kind: ScopeTag, Value: "vip", From: int64Ptr(1)}}, false},
+		{"long value", []Scope{{Kind: ScopeTag, Value: strings.Repeat("x", 101)}}, false},
+		{"unknown kind", []Scope{{Kind: "owner", Value: "x"}}, false},
+		{"too many", make([]Scope, 101), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateScopes(v, tt.scopes)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("Valid = %v, want %v (errors: %v)", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"time"
 )
 
+var (
+	ErrUnknownTariff = errors.New("unknown tariff")
+)
+
 // AccountTariffLink represents a tariff assignment to an account
 type AccountTariffLink struct {
 	ID        int64     `json:"id"`
@@ -137,8 +141,56 @@ func (m AccountTariffLinkModel) GetByAccountID(accountID int64) (*AccountTariffL
 }
 
 // Update changes the tariff with optimistic locking
+// and records the change in account_tariff_history within the same transaction
 // Returns ErrEditConflict if version doesn't match
 func (m AccountTariffLinkModel) Update(link *AccountTariffLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateTariffLinkTx(ctx, tx, link)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateTariffLinkTx performs the versioned update and writes the history row
+// Shared by direct changes and scheduled changes
+func updateTariffLinkTx(ctx context.Context, tx *sql.Tx, link *AccountTariffLink) error {
+	// Lock the row so the old tariff in history matches what was overwritten
+	lockQuery := `
+		SELECT account_id, tariff_id, version
+		FROM account_tariff_link
+		WHERE id = $1
+		FOR UPDATE`
+
+	var oldTariffID, currentVersion int64
+
+	err := tx.QueryRowContext(ctx, lockQuery, link.ID).Scan(
+		&link.AccountID,
+		&oldTariffID,
+		&currentVersion,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if currentVersion != link.Version {
+		return ErrEditConflict
+	}
+
 	query := `
 		UPDATE account_tariff_link
 		SET 
@@ -149,10 +201,7 @@ func (m AccountTariffLinkModel) Update(link *AccountTariffLink) error {
 		WHERE id = $3 AND version = $4
 		RETURNING version, updated_at`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		link.TariffID,
 		link.UpdatedBy,
 		link.ID,
@@ -160,11 +209,30 @@ func (m AccountTariffLinkModel) Update(link *AccountTariffLink) error {
 	).Scan(&link.Version, &link.UpdatedAt)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
+		case err.Error() == `pq: insert or update on table "account_tariff_link" violates foreign key constraint "account_tariff_link_tariff_id_fkey"`:
+			return ErrUnknownTariff
+		default:
+			return err
 		}
-		return err
 	}
 
-	return nil
+	historyQuery := `
+		INSERT INTO account_tariff_history
+			(link_id, account_id, old_tariff_id, new_tariff_id, version, changed_by, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = tx.ExecContext(ctx, historyQuery,
+		link.ID,
+		link.AccountID,
+		oldTariffID,
+		link.TariffID,
+		link.Version,
+		link.UpdatedBy,
+		link.UpdatedAt,
+	)
+
+	return err
 }
@@ -0,0 +1,214 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"biling_api/internal/validator"
+)
+
+var (
+	ErrTariffInUse = errors.New("tariff in use")
+)
+
+// Tariff represents a tariff plan from the catalog
+type Tariff struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Price       Money     `json:"price"`
+	IsActive    bool      `json:"is_active"`
+	Version     int64     `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ValidateTariff checks tariff fields before they are written
+func ValidateTariff(v *validator.Validator, tariff *Tariff) {
+	v.Check(tariff.Name != "", "name", "must be provided")
+	v.Check(len(tariff.Name) <= 255, "name", "must not be more than 255 bytes long")
+
+	v.Check(len(tariff.Description) <= 4096, "description", "must not be more than 4096 bytes long")
+
+	v.Check(tariff.Price >= 0, "price", "must not be negative")
+	v.Check(tariff.Price <= MaxMoney, "price", "is too large")
+}
+
+// TariffModel handles database operations for the tariff catalog
+type TariffModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a new tariff to the catalog
+func (m TariffModel) Insert(tariff *Tariff) error {
+	query := `
+		INSERT INTO tariffs (name, description, price, is_active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, version, created_at, updated_at`
+
+	args := []interface{}{tariff.Name, tariff.Description, tariff.Price, tariff.IsActive}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&tariff.ID,
+		&tariff.Version,
+		&tariff.CreatedAt,
+		&tariff.UpdatedAt,
+	)
+}
+
+// Get fetches a tariff by ID
+func (m TariffModel) Get(id int64) (*Tariff, error) {
+	query := `
+		SELECT id, name, description, price, is_active, version, created_at, updated_at
+		FROM tariffs
+		WHERE id = $1`
+
+	var tariff Tariff
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&tariff.ID,
+		&tariff.Name,
+		&tariff.Description,
+		&tariff.Price,
+		&tariff.IsActive,
+		&tariff.Version,
+		&tariff.CreatedAt,
+		&tariff.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tariff, nil
+}
+
+// GetAll fetches the whole catalog ordered by ID
+func (m TariffModel) GetAll() ([]*Tariff, error) {
+	query := `
+		SELECT id, name, description, price, is_active, version, created_at, updated_at
+		FROM tariffs
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tariffs := []*Tariff{}
+
+	for rows.Next() {
+		var tariff Tariff
+
+		err := rows.Scan(
+			&tariff.ID,
+			&tariff.Name,
+			&tariff.Description,
+			&tariff.Price,
+			&tariff.IsActive,
+			&tariff.Version,
+			&tariff.CreatedAt,
+			&tariff.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tariffs = append(tariffs, &tariff)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tariffs, nil
+}
+
+// Update replaces tariff fields with optimistic locking
+// Returns ErrEditConflict if version doesn't match
+func (m TariffModel) Update(tariff *Tariff) error {
+	query := `
+		UPDATE tariffs
+		SET
+			name = $1,
+			description = $2,
+			price = $3,
+			is_active = $4,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $5 AND version = $6
+		RETURNING version, updated_at`
+
+	args := []interface{}{
+		tariff.Name,
+		tariff.Description,
+		tariff.Price,
+		tariff.IsActive,
+		tariff.ID,
+		tariff.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&tariff.Version, &tariff.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a tariff from the catalog
+func (m TariffModel) Delete(id int64) error {
+	query := `
+		DELETE FROM tariffs
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), `pq: update or delete on table "tariffs" violates foreign key constraint`):
+			return ErrTariffInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AccountTariffChange is a single row of account_tariff_history
+type AccountTariffChange struct {
+	ID            int64          `json:"id"`
+	LinkID        int64          `json:"link_id"`
+	AccountID     int64          `json:"account_id"`
+	OldTariffID   int64          `json:"old_tariff_id"`
+	NewTariffID   int64          `json:"new_tariff_id"`
+	Version       int64          `json:"version"`
+	ChangedAt     time.Time      `json:"changed_at"`
+	ChangedBy     *int64         `json:"changed_by,omitempty"`
+	ChangedByUser *UpdatedByUser `json:"changed_by_user,omitempty"`
+}
+
+// AccountTariffHistoryModel reads the append-only tariff change history
+type AccountTariffHistoryModel struct {
+	DB *sql.DB
+}
+
+// GetByLinkID returns every change of a tariff link, oldest first
+func (m AccountTariffHistoryModel) GetByLinkID(linkID int64) ([]*AccountTariffChange, error) {
+	query := `
+		SELECT
+			h.id, h.link_id, h.account_id, h.old_tariff_id, h.new_tariff_id,
+			h.version, h.changed_at, h.changed_by,
+			au.id, au.login
+		FROM account_tariff_history h
+		LEFT JOIN system_accounts au ON h.changed_by = au.id
+		WHERE h.link_id = $1
+		ORDER BY h.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, linkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*AccountTariffChange{}
+
+	for rows.Next() {
+		var change AccountTariffChange
+		var changedByID sql.NullInt64
+		var userID sql.NullInt64
+		var userLogin sql.NullString
+
+		err := rows.Scan(
+			&change.ID,
+			&change.LinkID,
+			&change.AccountID,
+			&change.OldTariffID,
+			&change.NewTariffID,
+			&change.Version,
+			&change.ChangedAt,
+			&changedByID,
+			&userID,
+			&userLogin,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if changedByID.Valid {
+			change.ChangedBy = &changedByID.Int64
+		}
+
+		if userID.Valid && userLogin.Valid {
+			change.ChangedByUser = &UpdatedByUser{
+				ID:    userID.Int64,
+				Login: userLogin.String,
+			}
+		}
+
+		changes = append(changes, &change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,335 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrDuplicatePendingChange = errors.New("duplicate pending change")
+	ErrChangeNotPending       = errors.New("change not pending")
+	ErrNothingDue             = errors.New("no scheduled change is due")
+)
+
+// Scheduled tariff change statuses
+const (
+	ScheduledChangePending   = "pending"
+	ScheduledChangeApplied   = "applied"
+	ScheduledChangeCancelled = "cancelled"
+	ScheduledChangeFailed    = "failed"
+)
+
+// ScheduledTariffChange is a future-dated tariff change for an account tariff link
+type ScheduledTariffChange struct {
+	ID              int64      `json:"id"`
+	LinkID          int64      `json:"link_id"`
+	TariffID        int64      `json:"tariff_id"`
+	ExpectedVersion int64      `json:"expected_version"`
+	EffectiveAt     time.Time  `json:"effective_at"`
+	Status          string     `json:"status"`
+	FailureReason   string     `json:"failure_reason,omitempty"`
+	CreatedBy       *int64     `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CancelledBy     *int64     `json:"cancelled_by,omitempty"`
+	ProcessedAt     *time.Time `json:"processed_at,omitempty"`
+}
+
+// ScheduledTariffChangeModel handles database operations for account_tariff_schedule
+type ScheduledTariffChangeModel struct {
+	DB *sql.DB
+}
+
+const scheduledChangeColumns = `
+	id, link_id, tariff_id, expected_version, effective_at, status,
+	failure_reason, created_by, created_at, cancelled_by, processed_at`
+
+// scanScheduledChange reads a row selected with scheduledChangeColumns
+func scanScheduledChange(row interface{ Scan(...interface{}) error }) (*ScheduledTariffChange, error) {
+	var change ScheduledTariffChange
+	var createdBy, cancelledBy sql.NullInt64
+	var processedAt sql.NullTime
+
+	err := row.Scan(
+		&change.ID,
+		&change.LinkID,
+		&change.TariffID,
+		&change.ExpectedVersion,
+		&change.EffectiveAt,
+		&change.Status,
+		&change.FailureReason,
+		&createdBy,
+		&change.CreatedAt,
+		&cancelledBy,
+		&processedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		change.CreatedBy = &createdBy.Int64
+	}
+
+	if cancelledBy.Valid {
+		change.CancelledBy = &cancelledBy.Int64
+	}
+
+	if processedAt.Valid {
+		change.ProcessedAt = &processedAt.Time
+	}
+
+	return &change, nil
+}
+
+// Insert schedules a change if the link is still at the expected version
+// Returns ErrEditConflict if version doesn't match
+func (m ScheduledTariffChangeModel) Insert(change *ScheduledTariffChange) error {
+	query := `
+		INSERT INTO account_tariff_schedule
+			(link_id, tariff_id, expected_version, effective_at, created_by)
+		SELECT atl.id, $2, atl.version, $4, $5
+		FROM account_tariff_link atl
+		WHERE atl.id = $1 AND atl.version = $3
+		RETURNING id, status, created_at`
+
+	args := []interface{}{
+		change.LinkID,
+		change.TariffID,
+		change.ExpectedVersion,
+		change.EffectiveAt,
+		change.CreatedBy,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&change.ID,
+		&change.Status,
+		&change.CreatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "account_tariff_schedule_pending_key"`:
+			return ErrDuplicatePendingChange
+		case err.Error() == `pq: insert or update on table "account_tariff_schedule" violates foreign key constraint "account_tariff_schedule_tariff_id_fkey"`:
+			return ErrUnknownTariff
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get fetches a scheduled change by ID
+func (m ScheduledTariffChangeModel) Get(id int64) (*ScheduledTariffChange, error) {
+	query := `SELECT ` + scheduledChangeColumns + `
+		FROM account_tariff_schedule
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	change, err := scanScheduledChange(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return change, nil
+}
+
+// GetByLinkID lists all scheduled changes of a link, pending first by effective date
+func (m ScheduledTariffChangeModel) GetByLinkID(linkID int64) ([]*ScheduledTariffChange, error) {
+	query := `SELECT ` + scheduledChangeColumns + `
+		FROM account_tariff_schedule
+		WHERE link_id = $1
+		ORDER BY status <> 'pending', effective_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, linkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*ScheduledTariffChange{}
+
+	for rows.Next() {
+		change, err := scanScheduledChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// Cancel marks a pending change as cancelled
+// Returns ErrChangeNotPending if it was already applied, failed or cancelled
+func (m ScheduledTariffChangeModel) Cancel(id, cancelledBy int64) (*ScheduledTariffChange, error) {
+	query := `
+		UPDATE account_tariff_schedule
+		SET status = 'cancelled', cancelled_by = $2, processed_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING ` + scheduledChangeColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	change, err := scanScheduledChange(m.DB.QueryRowContext(ctx, query, id, cancelledBy))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		// Distinguish a missing change from one that is no longer pending
+		_, err = m.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrChangeNotPending
+	}
+
+	return change, nil
+}
+
+// ApplyNextDue applies the oldest due pending change
+// A change whose link moved past expected_version, or whose tariff was
+// deactivated, is marked as failed instead of being applied; so is a change
+// that fails with an unexpected error, so it doesn't block the ones after it
+// Returns ErrNothingDue when no pending change is due
+func (m ScheduledTariffChangeModel) ApplyNextDue() (*ScheduledTariffChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets several API instances run the applier concurrently
+	query := `SELECT ` + scheduledChangeColumns + `
+		FROM account_tariff_schedule
+		WHERE status = 'pending' AND effective_at <= NOW()
+		ORDER BY effective_at, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`
+
+	change, err := scanScheduledChange(tx.QueryRowContext(ctx, query))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNothingDue
+		default:
+			return nil, err
+		}
+	}
+
+	// The savepoint keeps the row lock when the apply itself has to be rolled back
+	_, err = tx.ExecContext(ctx, `SAVEPOINT apply_change`)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyScheduledChangeTx(ctx, tx, change)
+	if err != nil {
+		_, rollbackErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT apply_change`)
+		if rollbackErr != nil {
+			return nil, rollbackErr
+		}
+
+		change.Status = ScheduledChangeFailed
+		change.FailureReason = truncate("could not apply: "+err.Error(), 255)
+	}
+
+	updateQuery := `
+		UPDATE account_tariff_schedule
+		SET status = $2, failure_reason = $3, processed_at = NOW()
+		WHERE id = $1
+		RETURNING processed_at`
+
+	var processedAt time.Time
+	err = tx.QueryRowContext(ctx, updateQuery, change.ID, change.Status, change.FailureReason).Scan(&processedAt)
+	if err != nil {
+		return nil, err
+	}
+	change.ProcessedAt = &processedAt
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// applyScheduledChangeTx moves the link to the scheduled tariff and sets the change status
+// Expected failures are recorded on the change; the returned error is for anything else
+func applyScheduledChangeTx(ctx context.Context, tx *sql.Tx, change *ScheduledTariffChange) error {
+	var isActive bool
+	err := tx.QueryRowContext(ctx, `SELECT is_active FROM tariffs WHERE id = $1`, change.TariffID).Scan(&isActive)
+	if err != nil {
+		return err
+	}
+
+	if !isActive {
+		change.Status = ScheduledChangeFailed
+		change.FailureReason = "tariff is not active"
+		return nil
+	}
+
+	link := &AccountTariffLink{
+		ID:        change.LinkID,
+		TariffID:  change.TariffID,
+		Version:   change.ExpectedVersion,
+		UpdatedBy: change.CreatedBy,
+	}
+
+	err = updateTariffLinkTx(ctx, tx, link)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEditConflict):
+			change.Status = ScheduledChangeFailed
+			change.FailureReason = "account tariff was modified after the change was scheduled"
+			return nil
+		default:
+			return err
+		}
+	}
+
+	change.Status = ScheduledChangeApplied
+	return nil
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
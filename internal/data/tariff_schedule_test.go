@@ -0,0 +1,27 @@
+package data
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		n     int
+		want  string
+	}{
+		{"shorter", "abc", 5, "abc"},
+		{"exact", "abcde", 5, "abcde"},
+		{"longer", "abcdef", 3, "abc"},
+		{"zero", "abc", 0, ""},
+		{"keeps whole runes", "тариф", 3, "т"},
+		{"rune boundary", "тариф", 4, "та"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.input, tt.n); got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.input, tt.n, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -12,30 +14,102 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 )
 
+// Token purposes; access tokens have no purpose
+const (
+	PurposeMFAChallenge = "mfa_challenge"
+)
+
 // TokenModel handles JWT token operations
+// Tokens are signed with the active key of Keys when one is configured,
+// otherwise with HS256 and Secret
 type TokenModel struct {
 	Secret string
+	Keys   *KeySet
 }
 
 // Claims represents JWT claims
+// AuthUserID and Login identify the subject whose permissions apply
+// RegisteredClaims.ID carries the jti used for revocation
 type Claims struct {
+	AuthUserID int64        `json:"auth_user_id"`
+	Login      string       `json:"login"`
+	Purpose    string       `json:"purpose,omitempty"`
+	Actor      *ActorClaims `json:"act,omitempty"` // Set on impersonation tokens
+	jwt.RegisteredClaims
+}
+
+// ActorClaims identify the system account that acts as the subject
+type ActorClaims struct {
 	AuthUserID int64  `json:"auth_user_id"`
 	Login      string `json:"login"`
-	jwt.RegisteredClaims
 }
 
 // GenerateToken creates a new JWT token for a user
 func (m TokenModel) GenerateToken(authUserID int64, login string, duration time.Duration) (string, error) {
-	claims := Claims{
+	return m.generate(authUserID, login, "", duration)
+}
+
+// GenerateChallengeToken creates a short-lived token that only proves the password step of login
+// It is rejected by the authenticate middleware
+func (m TokenModel) GenerateChallengeToken(authUserID int64, login string, duration time.Duration) (string, error) {
+	return m.generate(authUserID, login, PurposeMFAChallenge, duration)
+}
+
+// GenerateImpersonationToken creates an access token for subject on behalf of actor
+// It returns the claims too, so the caller can record the jti
+func (m TokenModel) GenerateImpersonationToken(subject, actor *AuthUser, duration time.Duration) (string, *Claims, error) {
+	claims, err := newClaims(subject.ID, subject.Login, "", &ActorClaims{AuthUserID: actor.ID, Login: actor.Login}, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, claims, nil
+}
+
+func (m TokenModel) generate(authUserID int64, login, purpose string, duration time.Duration) (string, error) {
+	claims, err := newClaims(authUserID, login, purpose, nil, duration)
+	if err != nil {
+		return "", err
+	}
+
+	return m.sign(claims)
+}
+
+func newClaims(authUserID int64, login, purpose string, actor *ActorClaims, duration time.Duration) (*Claims, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{
 		AuthUserID: authUserID,
 		Login:      login,
+		Purpose:    purpose,
+		Actor:      actor,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	return claims, nil
+}
+
+// sign signs claims with the active key or, without one, with HS256
+func (m TokenModel) sign(claims *Claims) (string, error) {
+	if key := m.Keys.Active(); key != nil {
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.KID
+		return token.SignedString(key.PrivateKey)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(m.Secret))
 }
@@ -45,9 +119,17 @@ func (m TokenModel) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Asymmetric tokens name their key; old keys stay valid until removed from the set
+		if kid, ok := token.Header["kid"].(string); ok {
+			return m.Keys.VerificationKey(kid, token.Method)
+		}
+
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		if m.Secret == "" {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
 		return []byte(m.Secret), nil
 	})
 
@@ -59,5 +141,22 @@ func (m TokenModel) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	// Tokens without jti can't be revoked
+	if claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
 	return claims, nil
 }
+
+// newTokenID returns a random jti
+func newTokenID() (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
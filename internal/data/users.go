@@ -3,13 +3,59 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+
+	"biling_api/internal/validator"
+)
+
+var (
+	ErrUserHasAccounts = errors.New("user has accounts")
 )
 
 // User represents a business user
+// Contact and document fields are empty when unknown
 type User struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	Email     string    `json:"email"`
+	Address   string    `json:"address"`
+	Passport  string    `json:"passport"`
+	TaxID     string    `json:"tax_id"`
+	CreatedBy *int64    `json:"created_by"`
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ValidateUser checks user fields before they are written
+func ValidateUser(v *validator.Validator, user *User) {
+	v.Check(strings.TrimSpace(user.Name) != "", "name", "must be provided")
+	v.Check(len(user.Name) <= 255, "name", "must not be more than 255 bytes long")
+
+	if user.Phone != "" {
+		v.Check(validator.Matches(user.Phone, validator.PhoneRX), "phone", "must be in international format, e.g. +992901234567")
+	}
+
+	if user.Email != "" {
+		v.Check(len(user.Email) <= 255, "email", "must not be more than 255 bytes long")
+		v.Check(validator.Matches(user.Email, validator.EmailRX), "email", "must be a valid email address")
+	}
+
+	v.Check(len(user.Address) <= 1000, "address", "must not be more than 1000 bytes long")
+	v.Check(len(user.Passport) <= 50, "passport", "must not be more than 50 bytes long")
+	v.Check(len(user.TaxID) <= 50, "tax_id", "must not be more than 50 bytes long")
+}
+
+// UserSearch holds the optional search terms of the user list
+// Each term matches a substring, case-insensitively; all given terms must match
+type UserSearch struct {
+	Name  string
+	Phone string
+	Email string
 }
 
 // UserModel wraps database connection
@@ -17,31 +63,239 @@ type UserModel struct {
 	DB *sql.DB
 }
 
-// Get fetches a user by ID
-func (m UserModel) Get(id int64) (*User, error) {
-	query := `
-		SELECT id, name
-		FROM users
-		WHERE id = $1`
+// userColumns are selected by every query that returns a full User
+const userColumns = `id, name, phone, email, address, passport, tax_id, created_by, version, created_at, updated_at`
 
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
 	var user User
+	var createdBy sql.NullInt64
+
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Phone,
+		&user.Email,
+		&user.Address,
+		&user.Passport,
+		&user.TaxID,
+		&createdBy,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		user.CreatedBy = &createdBy.Int64
+	}
+
+	return &user, nil
+}
+
+// Insert adds a new user
+func (m UserModel) Insert(user *User) error {
+	query := `
+		INSERT INTO users (name, phone, email, address, passport, tax_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, version, created_at, updated_at`
+
+	args := []interface{}{user.Name, user.Phone, user.Email, user.Address, user.Passport, user.TaxID, user.CreatedBy}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
-		&user.Name,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
 	)
+}
+
+// Get fetches a user by ID
+func (m UserModel) Get(id int64) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1`
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	user, err := scanUser(m.DB.QueryRowContext(ctx, query, id))
 	if err != nil {
 		switch {
-		case err == sql.ErrNoRows:
+		case errors.Is(err, sql.ErrNoRows):
 			return nil, ErrRecordNotFound
 		default:
 			return nil, err
 		}
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+// GetAll returns one page of users matching search
+// Only users with at least one account covered by scope are returned,
+// and users without accounts that were created by createdBy
+func (m UserModel) GetAll(search UserSearch, scope *AccessScope, createdBy int64, filters Filters) ([]*User, Metadata, error) {
+	args := []interface{}{}
+
+	conditions := []string{"TRUE"}
+
+	for _, term := range []struct {
+		column string
+		value  string
+	}{
+		{"u.name", search.Name},
+		{"u.phone", search.Phone},
+		{"u.email", search.Email},
+	} {
+		if term.value != "" {
+			args = append(args, containsPattern(term.value))
+			conditions = append(conditions, fmt.Sprintf("%s ILIKE $%d", term.column, len(args)))
+		}
+	}
+
+	if scope == nil || !scope.Unrestricted {
+		args = append(args, createdBy)
+		createdByParam := len(args)
+
+		conditions = append(conditions, fmt.Sprintf(`(EXISTS (
+			SELECT 1
+			FROM users_accounts ua
+			INNER JOIN accounts a ON a.id = ua.account_id
+			WHERE ua.uid = u.id AND %s
+		) OR (u.created_by = $%d AND NOT EXISTS (
+			SELECT 1 FROM users_accounts ua WHERE ua.uid = u.id
+		)))`, scope.accountCondition(&args), createdByParam))
+	}
+
+	where := strings.Join(conditions, "\n\t\t  AND ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// Counted separately so that a page past the end still reports the total
+	totalRecords := 0
+
+	err := m.DB.QueryRowContext(ctx, `
+		SELECT count(*)
+		FROM users u
+		WHERE `+where, args...).Scan(&totalRecords)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	args = append(args, filters.limit(), filters.offset())
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM users u
+		WHERE %s
+		ORDER BY u.%s %s, u.id ASC
+		LIMIT $%d OFFSET $%d`,
+		userColumns, where, filters.sortColumn(), filters.sortDirection(), len(args)-1, len(args))
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return users, metadata, nil
+}
+
+// Update replaces user fields with optimistic locking
+// Returns ErrEditConflict if version doesn't match
+func (m UserModel) Update(user *User) error {
+	query := `
+		UPDATE users
+		SET
+			name = $1,
+			phone = $2,
+			email = $3,
+			address = $4,
+			passport = $5,
+			tax_id = $6,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $7 AND version = $8
+		RETURNING version, updated_at`
+
+	args := []interface{}{
+		user.Name,
+		user.Phone,
+		user.Email,
+		user.Address,
+		user.Passport,
+		user.TaxID,
+		user.ID,
+		user.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version, &user.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a user without accounts
+// Returns ErrUserHasAccounts while accounts are still linked to the user
+func (m UserModel) Delete(id int64) error {
+	query := `
+		DELETE FROM users
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: update or delete on table "users" violates foreign key constraint "users_accounts_uid_fkey" on table "users_accounts"`:
+			return ErrUserHasAccounts
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
 }
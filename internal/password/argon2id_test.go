@@ -0,0 +1,127 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+// testArgon2id keeps the tests fast; the parameters don't matter for correctness
+var testArgon2id = Argon2id{Time: 1, Memory: 64, Threads: 1, KeyLen: 16, SaltLen: 8}
+
+func TestDecodeArgon2id(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		want    Argon2id
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+			want: Argon2id{Time: 3, Memory: 65536, Threads: 4, KeyLen: 24, SaltLen: 8},
+		},
+		{name: "empty", hash: "", wantErr: true},
+		{name: "argon2i", hash: "$argon2i$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$a2V5a2V5", wantErr: true},
+		{name: "old version", hash: "$argon2id$v=16$m=65536,t=3,p=4$c2FsdHNhbHQ$a2V5a2V5", wantErr: true},
+		{name: "missing version", hash: "$argon2id$m=65536,t=3,p=4$c2FsdHNhbHQ$a2V5a2V5", wantErr: true},
+		{name: "bad parameters", hash: "$argon2id$v=19$m=x,t=3,p=4$c2FsdHNhbHQ$a2V5a2V5", wantErr: true},
+		{name: "padded salt", hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA==$a2V5a2V5", wantErr: true},
+		{name: "bad key", hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$!!!", wantErr: true},
+		{name: "empty key", hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$", wantErr: true},
+		{name: "extra field", hash: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$a2V5a2V5$x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, salt, key, err := decodeArgon2id(tt.hash)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidArgon2Hash) {
+					t.Fatalf("decodeArgon2id(%q) error = %v, want ErrInvalidArgon2Hash", tt.hash, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decodeArgon2id(%q) unexpected error: %v", tt.hash, err)
+			}
+
+			if params != tt.want {
+				t.Errorf("params = %+v, want %+v", params, tt.want)
+			}
+
+			if string(salt) != "saltsalt" {
+				t.Errorf("salt = %q, want %q", salt, "saltsalt")
+			}
+
+			if uint32(len(key)) != tt.want.KeyLen {
+				t.Errorf("key has %d bytes, want %d", len(key), tt.want.KeyLen)
+			}
+		})
+	}
+}
+
+func TestArgon2idHashVerify(t *testing.T) {
+	hash, err := testArgon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !testArgon2id.Identifies(hash) {
+		t.Fatalf("hash %q is not identified as argon2id", hash)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"correct horse", true},
+		{"correct horse ", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		got, err := testArgon2id.Verify(hash, tt.password)
+		if err != nil {
+			t.Fatalf("Verify(%q): unexpected error: %v", tt.password, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("Verify(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+
+	if _, err := testArgon2id.Verify("$argon2id$broken", "correct horse"); err == nil {
+		t.Error("Verify of a malformed hash: expected an error")
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	hash, err := testArgon2id.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hasher Argon2id
+		hash   string
+		want   bool
+	}{
+		{"same parameters", testArgon2id, hash, false},
+		{"more passes", Argon2id{Time: 2, Memory: 64, Threads: 1, KeyLen: 16, SaltLen: 8}, hash, true},
+		{"more memory", Argon2id{Time: 1, Memory: 128, Threads: 1, KeyLen: 16, SaltLen: 8}, hash, true},
+		{"more threads", Argon2id{Time: 1, Memory: 64, Threads: 2, KeyLen: 16, SaltLen: 8}, hash, true},
+		{"longer key", Argon2id{Time: 1, Memory: 64, Threads: 1, KeyLen: 32, SaltLen: 8}, hash, true},
+		{"longer salt", Argon2id{Time: 1, Memory: 64, Threads: 1, KeyLen: 16, SaltLen: 16}, hash, true},
+		{"malformed hash", testArgon2id, "$argon2id$broken", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
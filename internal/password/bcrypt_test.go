@@ -0,0 +1,89 @@
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	hash, err := Bcrypt{Cost: bcrypt.MinCost}.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cost int
+		hash string
+		want bool
+	}{
+		{"same cost", bcrypt.MinCost, hash, false},
+		{"higher cost", bcrypt.MinCost + 1, hash, true},
+		{"not a bcrypt hash", bcrypt.MinCost, "plain", true},
+		{"empty hash", bcrypt.MinCost, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Bcrypt{Cost: tt.cost}).NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBcryptVerify(t *testing.T) {
+	b := Bcrypt{Cost: bcrypt.MinCost}
+
+	hash, err := b.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+		wantErr  bool
+	}{
+		{"match", hash, "secret", true, false},
+		{"mismatch", hash, "Secret", false, false},
+		{"malformed hash", "$2a$broken", "secret", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.Verify(tt.hash, tt.password)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBcryptIdentifies(t *testing.T) {
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{"$2a$12$abc", true},
+		{"$2b$12$abc", true},
+		{"$2y$12$abc", true},
+		{"$2x$12$abc", false},
+		{"$argon2id$v=19$m=65536,t=3,p=4$salt$key", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := (Bcrypt{}).Identifies(tt.hash); got != tt.want {
+			t.Errorf("Identifies(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+)
+
+// Supported algorithm names for configuration
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Hasher hashes passwords and verifies them against stored hashes
+type Hasher interface {
+	// Hash returns an encoded hash that includes the algorithm and its parameters
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash; a mismatch is not an error
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash was created with other parameters than the current ones
+	NeedsRehash(hash string) bool
+	// Identifies reports whether hash was produced by this algorithm
+	Identifies(hash string) bool
+}
+
+// Default is used when nothing else is configured: bcrypt with cost 12
+var Default = New(Bcrypt{Cost: DefaultBcryptCost})
+
+// New returns a Hasher that creates new hashes with current
+// and still verifies hashes of every other supported algorithm,
+// so existing passwords keep working after the algorithm is changed
+func New(current Hasher) Hasher {
+	return dispatcher{
+		current: current,
+		all:     []Hasher{current, Bcrypt{Cost: DefaultBcryptCost}, DefaultArgon2id},
+	}
+}
+
+// FromConfig builds a Hasher for the named algorithm
+func FromConfig(algorithm string, bcryptCost int, argon Argon2id) (Hasher, error) {
+	switch algorithm {
+	case AlgorithmBcrypt:
+		h := Bcrypt{Cost: bcryptCost}
+		if err := h.check(); err != nil {
+			return nil, err
+		}
+		return New(h), nil
+	case AlgorithmArgon2id:
+		if err := argon.check(); err != nil {
+			return nil, err
+		}
+		return New(argon), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+// dispatcher picks the algorithm by the hash format
+type dispatcher struct {
+	current Hasher
+	all     []Hasher
+}
+
+func (d dispatcher) Hash(password string) (string, error) {
+	return d.current.Hash(password)
+}
+
+func (d dispatcher) Verify(hash, password string) (bool, error) {
+	for _, h := range d.all {
+		if h.Identifies(hash) {
+			return h.Verify(hash, password)
+		}
+	}
+
+	return false, ErrUnknownAlgorithm
+}
+
+// NeedsRehash is true for hashes of another algorithm or with outdated parameters
+func (d dispatcher) NeedsRehash(hash string) bool {
+	if !d.current.Identifies(hash) {
+		return true
+	}
+
+	return d.current.NeedsRehash(hash)
+}
+
+func (d dispatcher) Identifies(hash string) bool {
+	for _, h := range d.all {
+		if h.Identifies(hash) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestDispatcherNeedsRehash(t *testing.T) {
+	bcryptHash, err := Bcrypt{Cost: bcrypt.MinCost}.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argonHash, err := testArgon2id.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		current Hasher
+		hash    string
+		want    bool
+	}{
+		{"bcrypt, same cost", Bcrypt{Cost: bcrypt.MinCost}, bcryptHash, false},
+		{"bcrypt, cost raised", Bcrypt{Cost: bcrypt.MinCost + 1}, bcryptHash, true},
+		{"bcrypt to argon2id", testArgon2id, bcryptHash, true},
+		{"argon2id, same parameters", testArgon2id, argonHash, false},
+		{"argon2id to bcrypt", Bcrypt{Cost: bcrypt.MinCost}, argonHash, true},
+		{"unknown format", Bcrypt{Cost: bcrypt.MinCost}, "plain", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.current).NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherVerifiesEveryAlgorithm(t *testing.T) {
+	bcryptHash, err := Bcrypt{Cost: bcrypt.MinCost}.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argonHash, err := testArgon2id.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := New(testArgon2id)
+
+	for _, hash := range []string{bcryptHash, argonHash} {
+		ok, err := h.Verify(hash, "secret")
+		if err != nil || !ok {
+			t.Errorf("Verify(%q) = %v, %v; want true, nil", hash, ok, err)
+		}
+	}
+
+	if _, err := h.Verify("plain", "secret"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Verify of an unknown format: error = %v, want ErrUnknownAlgorithm", err)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		cost      int
+		argon     Argon2id
+		wantErr   bool
+	}{
+		{"bcrypt", AlgorithmBcrypt, DefaultBcryptCost, DefaultArgon2id, false},
+		{"bcrypt cost too low", AlgorithmBcrypt, bcrypt.MinCost - 1, DefaultArgon2id, true},
+		{"bcrypt cost too high", AlgorithmBcrypt, bcrypt.MaxCost + 1, DefaultArgon2id, true},
+		{"argon2id", AlgorithmArgon2id, DefaultBcryptCost, DefaultArgon2id, false},
+		{"argon2id without passes", AlgorithmArgon2id, DefaultBcryptCost, Argon2id{Memory: 64, Threads: 1, KeyLen: 16, SaltLen: 8}, true},
+		{"argon2id memory below threads", AlgorithmArgon2id, DefaultBcryptCost, Argon2id{Time: 1, Memory: 8, Threads: 2, KeyLen: 16, SaltLen: 8}, true},
+		{"argon2id short key", AlgorithmArgon2id, DefaultBcryptCost, Argon2id{Time: 1, Memory: 64, Threads: 1, KeyLen: 8, SaltLen: 8}, true},
+		{"unknown", "md5", DefaultBcryptCost, DefaultArgon2id, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromConfig(tt.algorithm, tt.cost, tt.argon)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FromConfig error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
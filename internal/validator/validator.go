@@ -4,6 +4,14 @@ import (
 	"regexp"
 )
 
+var (
+	// EmailRX is the pattern recommended by the W3C for email addresses
+	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+
+	// PhoneRX matches phone numbers in international format, e.g. +992901234567
+	PhoneRX = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
 // Validator holds validation errors
 type Validator struct {
 	Errors map[string]string
@@ -49,8 +57,8 @@ func Matches(value string, rx *regexp.Regexp) bool {
 }
 
 // Unique returns true if all values in a slice are unique
-func Unique(values []string) bool {
-	uniqueValues := make(map[string]bool)
+func Unique[T comparable](values []T) bool {
+	uniqueValues := make(map[T]bool)
 	for _, value := range values {
 		uniqueValues[value] = true
 	}
@@ -0,0 +1,7 @@
+-- migrations/000003_tariff_catalog.down.sql
+
+DELETE FROM system_rights WHERE fid IN (4, 5, 6);
+
+ALTER TABLE tariffs
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS is_active;
@@ -0,0 +1,13 @@
+-- migrations/000003_tariff_catalog.up.sql
+
+-- Каталог тарифов: признак активности и версия для оптимистичной блокировки
+ALTER TABLE tariffs
+    ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+-- Права на управление каталогом для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 4),  -- FID 4: создание тарифов
+    (1, 5),  -- FID 5: редактирование тарифов
+    (1, 6)   -- FID 6: удаление тарифов
+ON CONFLICT DO NOTHING;
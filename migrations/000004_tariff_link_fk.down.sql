@@ -0,0 +1,4 @@
+-- migrations/000004_tariff_link_fk.down.sql
+
+ALTER TABLE account_tariff_link
+    DROP CONSTRAINT IF EXISTS account_tariff_link_tariff_id_fkey;
@@ -0,0 +1,6 @@
+-- migrations/000004_tariff_link_fk.up.sql
+
+-- Запрещаем ссылки на несуществующие тарифы
+ALTER TABLE account_tariff_link
+    ADD CONSTRAINT account_tariff_link_tariff_id_fkey
+    FOREIGN KEY (tariff_id) REFERENCES tariffs(id);
@@ -0,0 +1,4 @@
+-- migrations/000005_account_tariff_history.down.sql
+
+DROP TABLE IF EXISTS account_tariff_history;
+DROP FUNCTION IF EXISTS account_tariff_history_append_only();
@@ -0,0 +1,26 @@
+-- migrations/000005_account_tariff_history.up.sql
+
+-- История смены тарифов (только добавление записей)
+CREATE TABLE account_tariff_history (
+    id BIGSERIAL PRIMARY KEY,
+    link_id INT NOT NULL REFERENCES account_tariff_link(id),
+    account_id INT NOT NULL REFERENCES accounts(id),
+    old_tariff_id INT NOT NULL REFERENCES tariffs(id),
+    new_tariff_id INT NOT NULL REFERENCES tariffs(id),
+    version BIGINT NOT NULL,
+    changed_by INT REFERENCES system_accounts(id),
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX account_tariff_history_link_id_idx ON account_tariff_history (link_id, id);
+
+-- Запрещаем изменение и удаление записей истории
+CREATE FUNCTION account_tariff_history_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'account_tariff_history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER account_tariff_history_append_only
+    BEFORE UPDATE OR DELETE ON account_tariff_history
+    FOR EACH ROW EXECUTE FUNCTION account_tariff_history_append_only();
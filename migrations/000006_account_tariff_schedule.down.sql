@@ -0,0 +1,3 @@
+-- migrations/000006_account_tariff_schedule.down.sql
+
+DROP TABLE IF EXISTS account_tariff_schedule;
@@ -0,0 +1,24 @@
+-- migrations/000006_account_tariff_schedule.up.sql
+
+-- Отложенные (запланированные) смены тарифа
+-- status: pending - ожидает, applied - применена, cancelled - отменена, failed - не удалось применить
+CREATE TABLE account_tariff_schedule (
+    id BIGSERIAL PRIMARY KEY,
+    link_id INT NOT NULL REFERENCES account_tariff_link(id),
+    tariff_id INT NOT NULL REFERENCES tariffs(id),
+    expected_version BIGINT NOT NULL,
+    effective_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    failure_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INT REFERENCES system_accounts(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    cancelled_by INT REFERENCES system_accounts(id),
+    processed_at TIMESTAMP
+);
+
+-- Не более одной ожидающей смены на привязку
+CREATE UNIQUE INDEX account_tariff_schedule_pending_key
+    ON account_tariff_schedule (link_id) WHERE status = 'pending';
+
+CREATE INDEX account_tariff_schedule_due_idx
+    ON account_tariff_schedule (effective_at) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+-- migrations/000007_invoices.down.sql
+
+DELETE FROM system_rights WHERE fid = 7;
+
+DROP TABLE IF EXISTS invoice_lines;
+DROP TABLE IF EXISTS invoices;
@@ -0,0 +1,33 @@
+-- migrations/000007_invoices.up.sql
+
+-- Счета за расчётный период (месяц)
+-- status: open - не оплачен, paid - оплачен
+CREATE TABLE invoices (
+    id BIGSERIAL PRIMARY KEY,
+    account_id INT NOT NULL REFERENCES accounts(id),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    total DECIMAL(10, 2) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'TJS',
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (account_id, period_start)
+);
+
+-- Строки счёта
+CREATE TABLE invoice_lines (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    tariff_id INT REFERENCES tariffs(id),
+    description VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    unit_price DECIMAL(10, 2) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL
+);
+
+CREATE INDEX invoice_lines_invoice_id_idx ON invoice_lines (invoice_id);
+
+-- Право на просмотр счетов для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 7)   -- FID 7: просмотр счетов на оплату
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- migrations/000008_ledger.down.sql
+
+DELETE FROM system_rights WHERE fid = 8;
+
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_transactions;
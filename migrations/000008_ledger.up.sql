@@ -0,0 +1,52 @@
+-- migrations/000008_ledger.up.sql
+
+-- Проводки (двойная запись): каждая транзакция содержит равные суммы по дебету и кредиту
+CREATE TABLE ledger_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Строки проводок
+-- ledger_account: receivable - задолженность лицевого счёта, revenue - выручка, cash - поступления
+-- account_id заполнен для строк по лицевому счёту (receivable)
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+    account_id INT REFERENCES accounts(id),
+    ledger_account VARCHAR(30) NOT NULL,
+    direction VARCHAR(6) NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    invoice_id BIGINT REFERENCES invoices(id),
+    payment_id BIGINT,
+    created_by INT REFERENCES system_accounts(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ledger_entries_account_idx ON ledger_entries (account_id, ledger_account, id);
+CREATE INDEX ledger_entries_transaction_idx ON ledger_entries (transaction_id);
+
+-- Проводки по уже выставленным счетам, такие же, как биллинг делает при выставлении:
+-- Дт receivable / Кт revenue на сумму счёта, описание «Счёт за ГГГГ-ММ», дата - issued_at счёта
+-- Счета с нулевой суммой проводок не получают
+DO $$
+DECLARE
+    inv RECORD;
+    tx_id BIGINT;
+BEGIN
+    FOR inv IN SELECT id, account_id, total, currency, period_start, issued_at FROM invoices WHERE total > 0 ORDER BY id LOOP
+        INSERT INTO ledger_transactions (description, created_at)
+        VALUES ('Счёт за ' || to_char(inv.period_start, 'YYYY-MM'), inv.issued_at)
+        RETURNING id INTO tx_id;
+
+        INSERT INTO ledger_entries (transaction_id, account_id, ledger_account, direction, amount, currency, invoice_id, created_at) VALUES
+            (tx_id, inv.account_id, 'receivable', 'debit', inv.total, inv.currency, inv.id, inv.issued_at),
+            (tx_id, NULL, 'revenue', 'credit', inv.total, inv.currency, inv.id, inv.issued_at);
+    END LOOP;
+END $$;
+
+-- Право на просмотр баланса и проводок для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 8)   -- FID 8: просмотр баланса и проводок
+ON CONFLICT DO NOTHING;
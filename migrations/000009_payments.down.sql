@@ -0,0 +1,9 @@
+-- migrations/000009_payments.down.sql
+
+DELETE FROM system_rights WHERE fid = 9;
+
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_payment_id_fkey;
+
+DROP TABLE IF EXISTS payment_allocations;
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,35 @@
+-- migrations/000009_payments.up.sql
+
+-- Входящие платежи
+-- external_ref - идентификатор платежа во внешней системе, повторная запись с тем же значением не создаёт новый платёж
+CREATE TABLE payments (
+    id BIGSERIAL PRIMARY KEY,
+    account_id INT NOT NULL REFERENCES accounts(id),
+    method VARCHAR(30) NOT NULL,
+    external_ref VARCHAR(255) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    created_by INT REFERENCES system_accounts(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT payments_external_ref_key UNIQUE (external_ref)
+);
+
+-- Распределение платежей по счетам
+CREATE TABLE payment_allocations (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id BIGINT NOT NULL REFERENCES payments(id),
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    UNIQUE (payment_id, invoice_id)
+);
+
+CREATE INDEX payment_allocations_invoice_id_idx ON payment_allocations (invoice_id);
+
+ALTER TABLE ledger_entries
+    ADD CONSTRAINT ledger_entries_payment_id_fkey
+    FOREIGN KEY (payment_id) REFERENCES payments(id);
+
+-- Право на внесение платежей для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 9)   -- FID 9: внесение платежей
+ON CONFLICT DO NOTHING;
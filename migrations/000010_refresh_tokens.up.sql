@@ -0,0 +1,17 @@
+-- migrations/000010_refresh_tokens.up.sql
+
+-- Refresh-токены (храним только SHA-256 хеш)
+-- family_id объединяет цепочку ротаций одного входа; при повторном использовании токена отзывается вся цепочка
+CREATE TABLE refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    token_hash BYTEA NOT NULL UNIQUE,
+    family_id CHAR(32) NOT NULL,
+    user_id INT NOT NULL REFERENCES system_accounts(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
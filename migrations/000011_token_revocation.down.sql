@@ -0,0 +1,6 @@
+-- migrations/000011_token_revocation.down.sql
+
+DELETE FROM system_rights WHERE fid = 10;
+
+DROP TABLE IF EXISTS revoked_token_users;
+DROP TABLE IF EXISTS revoked_tokens;
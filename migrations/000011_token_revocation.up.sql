@@ -0,0 +1,23 @@
+-- migrations/000011_token_revocation.up.sql
+
+-- Отозванные access-токены (по jti); строки можно удалять после expires_at
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id INT REFERENCES system_accounts(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_by INT REFERENCES system_accounts(id),
+    revoked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Отзыв всех токенов системного пользователя, выданных раньше revoked_before
+CREATE TABLE revoked_token_users (
+    user_id INT PRIMARY KEY REFERENCES system_accounts(id),
+    revoked_before TIMESTAMPTZ NOT NULL,
+    revoked_by INT REFERENCES system_accounts(id),
+    revoked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Право на отзыв токенов для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 10)  -- FID 10: отзыв токенов
+ON CONFLICT DO NOTHING;
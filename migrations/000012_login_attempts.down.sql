@@ -0,0 +1,5 @@
+-- migrations/000012_login_attempts.down.sql
+
+DELETE FROM system_rights WHERE fid = 11;
+
+DROP TABLE IF EXISTS login_attempts;
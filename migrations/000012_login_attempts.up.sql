@@ -0,0 +1,17 @@
+-- migrations/000012_login_attempts.up.sql
+
+-- Неудачные попытки входа по логину и по IP
+-- kind: login | ip; locked_until - до какого момента вход заблокирован
+CREATE TABLE login_attempts (
+    kind VARCHAR(10) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (kind, key)
+);
+
+-- Право на снятие блокировки входа для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 11)  -- FID 11: снятие блокировки входа
+ON CONFLICT DO NOTHING;
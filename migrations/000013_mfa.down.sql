@@ -0,0 +1,7 @@
+-- migrations/000013_mfa.down.sql
+
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS system_account_mfa;
+
+ALTER TABLE system_group_info
+    DROP COLUMN IF EXISTS require_mfa;
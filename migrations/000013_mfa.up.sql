@@ -0,0 +1,32 @@
+-- migrations/000013_mfa.up.sql
+
+-- Обязательная двухфакторная аутентификация для участников группы
+ALTER TABLE system_group_info
+    ADD COLUMN require_mfa BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Включаем 2FA для групп, которым разрешено изменение тарифов (FID 3)
+UPDATE system_group_info
+SET require_mfa = TRUE
+WHERE id IN (SELECT group_id FROM system_rights WHERE fid = 3);
+
+-- TOTP-секрет системного пользователя
+-- enabled = FALSE, пока пользователь не подтвердил первый код
+-- last_used_step защищает от повторного использования кода
+CREATE TABLE system_account_mfa (
+    user_id INT PRIMARY KEY REFERENCES system_accounts(id),
+    totp_secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMP
+);
+
+-- Одноразовые коды восстановления (храним SHA-256 хеш)
+CREATE TABLE mfa_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES system_accounts(id),
+    code_hash BYTEA NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
@@ -0,0 +1,5 @@
+-- migrations/000014_system_account_admin.down.sql
+
+DELETE FROM system_rights WHERE fid IN (12, 13, 14, 15);
+
+DROP INDEX IF EXISTS system_accounts_login_key;
@@ -0,0 +1,15 @@
+-- migrations/000014_system_account_admin.up.sql
+
+-- Логин уникален только среди неудалённых системных пользователей,
+-- поэтому удалённый логин можно занять заново
+CREATE UNIQUE INDEX system_accounts_login_key
+    ON system_accounts (login)
+    WHERE is_deleted = 0;
+
+-- Права на управление системными пользователями для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 12), -- FID 12: просмотр системных пользователей
+    (1, 13), -- FID 13: создание системных пользователей
+    (1, 14), -- FID 14: редактирование системных пользователей
+    (1, 15)  -- FID 15: удаление и восстановление системных пользователей
+ON CONFLICT DO NOTHING;
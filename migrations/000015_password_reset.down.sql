@@ -0,0 +1,5 @@
+-- migrations/000015_password_reset.down.sql
+
+DELETE FROM system_rights WHERE fid = 16;
+
+DROP TABLE IF EXISTS password_reset_tokens;
@@ -0,0 +1,18 @@
+-- migrations/000015_password_reset.up.sql
+
+-- Одноразовые токены сброса пароля, выдаваемые администратором (храним только SHA-256 хеш)
+CREATE TABLE password_reset_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES system_accounts(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_by INT REFERENCES system_accounts(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    used_at TIMESTAMP
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+-- Право на сброс пароля для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 16)  -- FID 16: выдача токена сброса пароля
+ON CONFLICT DO NOTHING;
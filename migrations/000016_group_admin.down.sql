@@ -0,0 +1,11 @@
+-- migrations/000016_group_admin.down.sql
+
+DELETE FROM system_rights WHERE fid BETWEEN 17 AND 24;
+
+-- Переименованные при подъёме повторяющиеся названия групп не восстанавливаются
+DROP INDEX IF EXISTS system_group_info_name_key;
+
+ALTER TABLE system_groups DROP CONSTRAINT IF EXISTS system_groups_group_id_user_id_key;
+ALTER TABLE system_groups DROP CONSTRAINT IF EXISTS system_groups_user_id_fkey;
+ALTER TABLE system_groups DROP CONSTRAINT IF EXISTS system_groups_group_id_fkey;
+ALTER TABLE system_rights DROP CONSTRAINT IF EXISTS system_rights_group_id_fkey;
@@ -0,0 +1,53 @@
+-- migrations/000016_group_admin.up.sql
+
+-- Удаляем права и членства, ссылающиеся на несуществующие группы и пользователей
+DELETE FROM system_rights
+WHERE group_id NOT IN (SELECT id FROM system_group_info);
+
+DELETE FROM system_groups
+WHERE group_id NOT IN (SELECT id FROM system_group_info)
+   OR user_id NOT IN (SELECT id FROM system_accounts);
+
+-- Повторные членства в одной группе не имеют смысла
+DELETE FROM system_groups a
+USING system_groups b
+WHERE a.group_id = b.group_id AND a.user_id = b.user_id AND a.id > b.id;
+
+-- При удалении группы её права и членства удаляются вместе с ней
+ALTER TABLE system_rights
+    ADD CONSTRAINT system_rights_group_id_fkey
+    FOREIGN KEY (group_id) REFERENCES system_group_info(id) ON DELETE CASCADE;
+
+ALTER TABLE system_groups
+    ADD CONSTRAINT system_groups_group_id_fkey
+    FOREIGN KEY (group_id) REFERENCES system_group_info(id) ON DELETE CASCADE;
+
+ALTER TABLE system_groups
+    ADD CONSTRAINT system_groups_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES system_accounts(id);
+
+ALTER TABLE system_groups
+    ADD CONSTRAINT system_groups_group_id_user_id_key UNIQUE (group_id, user_id);
+
+-- Названия групп должны быть уникальны: повторы переименовываем, добавляя ID группы,
+-- название сохраняет группа с наименьшим ID
+UPDATE system_group_info g
+SET name = left(g.name, 255 - length(' #' || g.id)) || ' #' || g.id
+WHERE EXISTS (
+    SELECT 1 FROM system_group_info o
+    WHERE o.name = g.name AND o.id < g.id
+);
+
+CREATE UNIQUE INDEX system_group_info_name_key ON system_group_info (name);
+
+-- Права на администрирование групп для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 17), -- FID 17: просмотр групп
+    (1, 18), -- FID 18: создание групп
+    (1, 19), -- FID 19: переименование групп
+    (1, 20), -- FID 20: удаление групп
+    (1, 21), -- FID 21: выдача прав группе
+    (1, 22), -- FID 22: отзыв прав у группы
+    (1, 23), -- FID 23: добавление участников в группу
+    (1, 24)  -- FID 24: удаление участников из группы
+ON CONFLICT DO NOTHING;
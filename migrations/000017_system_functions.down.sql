@@ -0,0 +1,5 @@
+-- migrations/000017_system_functions.down.sql
+
+ALTER TABLE system_rights DROP CONSTRAINT IF EXISTS system_rights_fid_fkey;
+
+DROP TABLE IF EXISTS system_functions;
@@ -0,0 +1,51 @@
+-- migrations/000017_system_functions.up.sql
+
+-- Справочник FID: что разрешает каждое право
+-- Приложение синхронизирует его с реестром в коде при запуске,
+-- новые FID в миграциях нужно добавлять сюда до выдачи прав
+CREATE TABLE system_functions (
+    fid INT PRIMARY KEY,
+    code VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO system_functions (fid, code, name, category) VALUES
+    (1, 'accounts.read', 'Чтение аккаунтов', 'accounts'),
+    (2, 'tariffs.read', 'Чтение тарифов', 'tariffs'),
+    (3, 'tariffs.update', 'Обновление тарифов', 'tariffs'),
+    (4, 'tariff_catalog.create', 'Создание тарифов в каталоге', 'tariffs'),
+    (5, 'tariff_catalog.update', 'Редактирование тарифов в каталоге', 'tariffs'),
+    (6, 'tariff_catalog.delete', 'Удаление тарифов из каталога', 'tariffs'),
+    (7, 'invoices.read', 'Просмотр счетов на оплату', 'billing'),
+    (8, 'ledger.read', 'Просмотр баланса и проводок', 'billing'),
+    (9, 'payments.create', 'Внесение платежей', 'billing'),
+    (10, 'tokens.revoke', 'Отзыв токенов', 'auth'),
+    (11, 'login.unlock', 'Снятие блокировки входа', 'auth'),
+    (12, 'system_accounts.read', 'Просмотр системных пользователей', 'admin'),
+    (13, 'system_accounts.create', 'Создание системных пользователей', 'admin'),
+    (14, 'system_accounts.update', 'Редактирование системных пользователей', 'admin'),
+    (15, 'system_accounts.delete', 'Удаление и восстановление системных пользователей', 'admin'),
+    (16, 'password.reset', 'Выдача токена сброса пароля', 'auth'),
+    (17, 'groups.read', 'Просмотр групп', 'admin'),
+    (18, 'groups.create', 'Создание групп', 'admin'),
+    (19, 'groups.update', 'Переименование групп', 'admin'),
+    (20, 'groups.delete', 'Удаление групп', 'admin'),
+    (21, 'group_rights.grant', 'Выдача прав группе', 'admin'),
+    (22, 'group_rights.revoke', 'Отзыв прав у группы', 'admin'),
+    (23, 'group_members.add', 'Добавление участников в группу', 'admin'),
+    (24, 'group_members.remove', 'Удаление участников из группы', 'admin');
+
+-- Права с FID, которых нет в справочнике, сохраняем как неизвестные
+INSERT INTO system_functions (fid, code, name)
+SELECT DISTINCT fid, 'unknown.' || fid, 'Неизвестная функция ' || fid
+FROM system_rights
+ON CONFLICT DO NOTHING;
+
+-- Выдать можно только FID из справочника
+ALTER TABLE system_rights
+    ADD CONSTRAINT system_rights_fid_fkey
+    FOREIGN KEY (fid) REFERENCES system_functions(fid);
@@ -0,0 +1,7 @@
+-- migrations/000018_authz_notify.down.sql
+
+DROP TRIGGER IF EXISTS system_rights_authz_notify ON system_rights;
+DROP TRIGGER IF EXISTS system_groups_authz_notify_truncate ON system_groups;
+DROP TRIGGER IF EXISTS system_groups_authz_notify ON system_groups;
+
+DROP FUNCTION IF EXISTS notify_authz_changed();
@@ -0,0 +1,32 @@
+-- migrations/000018_authz_notify.up.sql
+
+-- Уведомление приложений об изменении прав и членства в группах
+-- Канал authz_changed, payload: 'user:<id>' - сбросить кеш одного пользователя, '*' - сбросить весь кеш
+CREATE FUNCTION notify_authz_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_LEVEL = 'ROW' AND TG_TABLE_NAME = 'system_groups' THEN
+        IF TG_OP IN ('UPDATE', 'DELETE') THEN
+            PERFORM pg_notify('authz_changed', 'user:' || OLD.user_id);
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM pg_notify('authz_changed', 'user:' || NEW.user_id);
+        END IF;
+    ELSE
+        PERFORM pg_notify('authz_changed', '*');
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER system_groups_authz_notify
+    AFTER INSERT OR UPDATE OR DELETE ON system_groups
+    FOR EACH ROW EXECUTE FUNCTION notify_authz_changed();
+
+CREATE TRIGGER system_groups_authz_notify_truncate
+    AFTER TRUNCATE ON system_groups
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_authz_changed();
+
+-- Изменение прав группы затрагивает всех её участников, поэтому сбрасываем весь кеш
+CREATE TRIGGER system_rights_authz_notify
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON system_rights
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_authz_changed();
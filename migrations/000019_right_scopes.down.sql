@@ -0,0 +1,10 @@
+-- migrations/000019_right_scopes.down.sql
+
+DROP TABLE IF EXISTS system_right_scopes;
+
+DROP INDEX IF EXISTS accounts_tags_idx;
+DROP INDEX IF EXISTS accounts_region_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS region;
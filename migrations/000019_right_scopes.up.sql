@@ -0,0 +1,37 @@
+-- migrations/000019_right_scopes.up.sql
+
+-- Регион и метки аккаунта для ограничения прав
+ALTER TABLE accounts
+    ADD COLUMN region VARCHAR(100),
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX accounts_region_idx ON accounts (region);
+CREATE INDEX accounts_tags_idx ON accounts USING GIN (tags);
+
+-- Ограничения выданного права (group_id, fid) частью аккаунтов
+-- Право без строк в этой таблице действует на все аккаунты;
+-- несколько строк объединяются: достаточно совпадения с любой
+-- kind: account_range (account_id_from..account_id_to) | region | tag (value)
+CREATE TABLE system_right_scopes (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL,
+    fid INT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    account_id_from BIGINT,
+    account_id_to BIGINT,
+    value VARCHAR(100),
+    FOREIGN KEY (group_id, fid) REFERENCES system_rights (group_id, fid) ON DELETE CASCADE,
+    CHECK (
+        (kind = 'account_range' AND account_id_from IS NOT NULL AND account_id_to IS NOT NULL
+            AND account_id_from <= account_id_to AND value IS NULL)
+        OR (kind IN ('region', 'tag') AND value IS NOT NULL
+            AND account_id_from IS NULL AND account_id_to IS NULL)
+    )
+);
+
+CREATE INDEX system_right_scopes_group_id_fid_idx ON system_right_scopes (group_id, fid);
+
+-- Изменение ограничений сбрасывает кеш прав (см. 000018_authz_notify)
+CREATE TRIGGER system_right_scopes_authz_notify
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON system_right_scopes
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_authz_changed();
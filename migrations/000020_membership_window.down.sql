@@ -0,0 +1,12 @@
+-- migrations/000020_membership_window.down.sql
+
+DROP TABLE IF EXISTS audit_log;
+
+DROP INDEX IF EXISTS system_groups_valid_until_idx;
+
+ALTER TABLE system_groups
+    DROP CONSTRAINT IF EXISTS system_groups_valid_window_check,
+    DROP COLUMN IF EXISTS granted_at,
+    DROP COLUMN IF EXISTS granted_by,
+    DROP COLUMN IF EXISTS valid_until,
+    DROP COLUMN IF EXISTS valid_from;
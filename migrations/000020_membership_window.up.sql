@@ -0,0 +1,29 @@
+-- migrations/000020_membership_window.up.sql
+
+-- Срок действия членства в группе и кто его выдал
+-- valid_from/valid_until = NULL - без ограничения с соответствующей стороны
+ALTER TABLE system_groups
+    ADD COLUMN valid_from TIMESTAMPTZ,
+    ADD COLUMN valid_until TIMESTAMPTZ,
+    ADD COLUMN granted_by INT REFERENCES system_accounts(id) ON DELETE SET NULL,
+    ADD COLUMN granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD CONSTRAINT system_groups_valid_window_check
+        CHECK (valid_from IS NULL OR valid_until IS NULL OR valid_from < valid_until);
+
+-- Для очистки истёкших членств
+CREATE INDEX system_groups_valid_until_idx ON system_groups (valid_until) WHERE valid_until IS NOT NULL;
+
+-- Журнал аудита административных действий
+-- actor_id = NULL - действие выполнено системой (например, фоновой задачей)
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INT REFERENCES system_accounts(id) ON DELETE SET NULL,
+    action VARCHAR(100) NOT NULL,
+    entity VARCHAR(50) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_log_entity_idx ON audit_log (entity, entity_id);
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
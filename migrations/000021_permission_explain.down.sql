@@ -0,0 +1,4 @@
+-- migrations/000021_permission_explain.down.sql
+
+DELETE FROM system_rights WHERE fid = 25;
+DELETE FROM system_functions WHERE fid = 25;
@@ -0,0 +1,10 @@
+-- migrations/000021_permission_explain.up.sql
+
+INSERT INTO system_functions (fid, code, name, category) VALUES
+    (25, 'permissions.explain', 'Разбор проверки прав', 'admin')
+ON CONFLICT DO NOTHING;
+
+-- Право на разбор проверки прав для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 25) -- FID 25: разбор проверки прав
+ON CONFLICT DO NOTHING;
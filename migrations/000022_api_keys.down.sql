@@ -0,0 +1,6 @@
+-- migrations/000022_api_keys.down.sql
+
+DELETE FROM system_rights WHERE fid BETWEEN 26 AND 28;
+DELETE FROM system_functions WHERE fid BETWEEN 26 AND 28;
+
+DROP TABLE IF EXISTS api_keys;
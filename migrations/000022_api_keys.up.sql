@@ -0,0 +1,34 @@
+-- migrations/000022_api_keys.up.sql
+
+-- API-ключи для межсервисных клиентов (храним только SHA-256 хеш)
+-- Ключ действует от имени системного пользователя;
+-- fids = NULL - все права пользователя, иначе только пересечение с этим списком
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    key_hash BYTEA NOT NULL UNIQUE,
+    prefix VARCHAR(20) NOT NULL,
+    user_id INT NOT NULL REFERENCES system_accounts(id),
+    name VARCHAR(255) NOT NULL,
+    fids INT[],
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_by INT NOT NULL REFERENCES system_accounts(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    revoked_by INT REFERENCES system_accounts(id)
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+
+INSERT INTO system_functions (fid, code, name, category) VALUES
+    (26, 'api_keys.read', 'Просмотр API-ключей', 'auth'),
+    (27, 'api_keys.create', 'Выпуск API-ключей', 'auth'),
+    (28, 'api_keys.revoke', 'Отзыв API-ключей', 'auth')
+ON CONFLICT DO NOTHING;
+
+-- Права на управление API-ключами для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 26), -- FID 26: просмотр API-ключей
+    (1, 27), -- FID 27: выпуск API-ключей
+    (1, 28)  -- FID 28: отзыв API-ключей
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- migrations/000023_impersonation.down.sql
+
+DELETE FROM system_rights WHERE fid = 29;
+DELETE FROM system_functions WHERE fid = 29;
@@ -0,0 +1,10 @@
+-- migrations/000023_impersonation.up.sql
+
+INSERT INTO system_functions (fid, code, name, category) VALUES
+    (29, 'impersonate', 'Вход от имени системного пользователя', 'auth')
+ON CONFLICT DO NOTHING;
+
+-- Право на вход от имени пользователя для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 29) -- FID 29: вход от имени системного пользователя
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,23 @@
+-- migrations/000024_user_profile.down.sql
+
+DELETE FROM system_rights WHERE fid BETWEEN 30 AND 33;
+DELETE FROM system_functions WHERE fid BETWEEN 30 AND 33;
+
+DROP INDEX IF EXISTS users_accounts_uid_idx;
+ALTER TABLE users_accounts DROP CONSTRAINT IF EXISTS users_accounts_uid_fkey;
+
+-- Возвращаем отложенные при подъёме связи
+INSERT INTO users_accounts (id, uid, account_id)
+SELECT id, uid, account_id FROM users_accounts_orphaned;
+
+DROP TABLE users_accounts_orphaned;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS tax_id,
+    DROP COLUMN IF EXISTS passport,
+    DROP COLUMN IF EXISTS address,
+    DROP COLUMN IF EXISTS email,
+    DROP COLUMN IF EXISTS phone;
@@ -0,0 +1,44 @@
+-- migrations/000024_user_profile.up.sql
+
+-- Контактные данные и документы бизнес-пользователя
+-- Пустая строка - значение не указано
+ALTER TABLE users
+    ADD COLUMN phone VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN email VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN passport VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN tax_id VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- Связи с несуществующими пользователями остались от ручных правок
+-- Переносим их в users_accounts_orphaned для ручного разбора, а не удаляем молча
+CREATE TABLE users_accounts_orphaned AS
+SELECT ua.*, NOW() AS quarantined_at
+FROM users_accounts ua
+WHERE ua.uid NOT IN (SELECT id FROM users);
+
+DELETE FROM users_accounts WHERE uid NOT IN (SELECT id FROM users);
+
+-- После этого пользователя с аккаунтами нельзя удалить, не отвязав их
+
+ALTER TABLE users_accounts
+    ADD CONSTRAINT users_accounts_uid_fkey FOREIGN KEY (uid) REFERENCES users(id);
+
+CREATE INDEX users_accounts_uid_idx ON users_accounts (uid);
+
+INSERT INTO system_functions (fid, code, name, category) VALUES
+    (30, 'users.read', 'Просмотр пользователей', 'accounts'),
+    (31, 'users.create', 'Создание пользователей', 'accounts'),
+    (32, 'users.update', 'Редактирование пользователей', 'accounts'),
+    (33, 'users.delete', 'Удаление пользователей', 'accounts')
+ON CONFLICT DO NOTHING;
+
+-- Права на работу с пользователями для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 30), -- FID 30: просмотр пользователей
+    (1, 31), -- FID 31: создание пользователей
+    (1, 32), -- FID 32: редактирование пользователей
+    (1, 33)  -- FID 33: удаление пользователей
+ON CONFLICT DO NOTHING;
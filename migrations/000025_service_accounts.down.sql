@@ -0,0 +1,3 @@
+-- migrations/000025_service_accounts.down.sql
+
+ALTER TABLE system_accounts DROP COLUMN IF EXISTS is_service;
@@ -0,0 +1,6 @@
+-- migrations/000025_service_accounts.up.sql
+
+-- Служебная учётная запись межсервисного клиента
+-- API-ключ можно выпустить только для своей учётной записи или для служебной
+ALTER TABLE system_accounts
+    ADD COLUMN is_service BOOLEAN NOT NULL DEFAULT FALSE;
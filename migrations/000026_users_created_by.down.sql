@@ -0,0 +1,3 @@
+-- migrations/000026_users_created_by.down.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS created_by;
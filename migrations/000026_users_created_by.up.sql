@@ -0,0 +1,6 @@
+-- migrations/000026_users_created_by.up.sql
+
+-- Кто завёл пользователя: оператору с ограниченным доступом его пользователь
+-- без аккаунтов иначе недоступен
+ALTER TABLE users
+    ADD COLUMN created_by INT REFERENCES system_accounts(id);
@@ -0,0 +1,4 @@
+-- migrations/000027_account_management.down.sql
+
+DELETE FROM system_rights WHERE fid BETWEEN 34 AND 35;
+DELETE FROM system_functions WHERE fid BETWEEN 34 AND 35;
@@ -0,0 +1,12 @@
+-- migrations/000027_account_management.up.sql
+
+INSERT INTO system_functions (fid, code, name, category) VALUES
+    (34, 'accounts.create', 'Создание аккаунтов', 'accounts'),
+    (35, 'accounts.update', 'Редактирование аккаунтов', 'accounts')
+ON CONFLICT DO NOTHING;
+
+-- Права на создание и редактирование аккаунтов для группы Администраторы (group_id=1)
+INSERT INTO system_rights (group_id, fid) VALUES
+    (1, 34), -- FID 34: создание аккаунтов
+    (1, 35)  -- FID 35: редактирование аккаунтов
+ON CONFLICT DO NOTHING;
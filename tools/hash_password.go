@@ -1,31 +1,51 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
-	"golang.org/x/crypto/bcrypt"
+	"biling_api/internal/password"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Использование: go run hash_password.go <password>")
+	algorithm := flag.String("algorithm", password.AlgorithmBcrypt, "Алгоритм хеширования (bcrypt|argon2id)")
+	cost := flag.Int("cost", password.DefaultBcryptCost, "bcrypt cost")
+	argon2Time := flag.Uint("argon2-time", uint(password.DefaultArgon2id.Time), "argon2id: число проходов")
+	argon2Memory := flag.Uint("argon2-memory", uint(password.DefaultArgon2id.Memory), "argon2id: память в KiB")
+	argon2Threads := flag.Uint("argon2-threads", uint(password.DefaultArgon2id.Threads), "argon2id: число потоков")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Println("Использование: go run hash_password.go [-algorithm bcrypt|argon2id] [-cost 12] <password>")
 		fmt.Println("Пример: go run hash_password.go password123")
+		fmt.Println("Пример: go run hash_password.go -algorithm argon2id password123")
+		os.Exit(1)
+	}
+
+	hasher, err := password.FromConfig(*algorithm, *cost, password.Argon2id{
+		Time:    uint32(*argon2Time),
+		Memory:  uint32(*argon2Memory),
+		Threads: uint8(*argon2Threads),
+		KeyLen:  password.DefaultArgon2id.KeyLen,
+		SaltLen: password.DefaultArgon2id.SaltLen,
+	})
+	if err != nil {
+		fmt.Printf("Ошибка параметров: %v\n", err)
 		os.Exit(1)
 	}
 
-	password := os.Args[1]
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	hash, err := hasher.Hash(flag.Arg(0))
 	if err != nil {
 		fmt.Printf("Ошибка генерации хеша: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("\n✅ Хеш пароля успешно сгенерирован (bcrypt cost 12):")
+	fmt.Printf("\n✅ Хеш пароля успешно сгенерирован (%s):\n", *algorithm)
 	fmt.Println()
-	fmt.Println(string(hash))
+	fmt.Println(hash)
 	fmt.Println()
 	fmt.Println("💡 Используйте этот хеш в SQL запросе:")
-	fmt.Printf("INSERT INTO system_accounts (login, password, name) VALUES ('username', '%s', 'Имя');\n", string(hash))
+	fmt.Printf("INSERT INTO system_accounts (login, password, name) VALUES ('username', '%s', 'Имя');\n", hash)
 	fmt.Println()
 }